@@ -4,18 +4,90 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"go.uber.org/fx"
 )
 
+// DefaultKillGrace is the time between SIGTERM and SIGKILL for a command that
+// timed out, when no grace is supplied or when the grace is nonpositive.
+const DefaultKillGrace time.Duration = 5 * time.Second
+
+const (
+	// SilentDetail is the Details key listing the expected sources, separated by
+	// commas, which went silent and caused a switch to trigger.
+	SilentDetail = "silent"
+
+	// QuorumDetail is the Details key holding the quorum required by a switch.
+	QuorumDetail = "quorum"
+
+	// LiveDetail is the Details key holding the weighted count of live sources
+	// at the time a quorum switch triggered.
+	LiveDetail = "live"
+
+	// PostponesDetail is the Details key holding the number of intervals with a
+	// postpone within a switch's miss budget window.
+	PostponesDetail = "postpones"
+
+	// IntervalsDetail is the Details key holding the number of intervals in a
+	// switch's miss budget window.
+	IntervalsDetail = "intervals"
+
+	// TierDetail is the Details key holding the escalation tier that fired.
+	TierDetail = "tier"
+
+	// MissesDetail is the Details key holding a switch's consecutive miss count.
+	MissesDetail = "misses"
+
+	// OutageDetail is the Details key holding the length of an outage, as a duration.
+	OutageDetail = "outage"
+
+	// SourceDetail is the Details key holding who manually triggered a switch, or
+	// the source of an unhealthy heartbeat which triggered a switch.
+	SourceDetail = "source"
+
+	// ReasonDetail is the Details key holding the reason a switch was manually triggered.
+	ReasonDetail = "reason"
+
+	// UnhealthyDetail is the Details key listing the rules, separated by commas,
+	// that an unhealthy heartbeat failed.
+	UnhealthyDetail = "unhealthy"
+)
+
 var (
 	// ErrEmptyCommand is returned by ParseExec to indicate that an exec action
 	// was blank or had a blank command path.
 	ErrEmptyCommand = errors.New("A non-empty command is required")
+
+	// ErrInvalidTier is returned by ParseTiers to indicate a malformed --tier value.
+	ErrInvalidTier = errors.New("A tier must be of the form misses=command")
+
+	// ErrInvalidExecPolicy is returned by ParseExec to indicate a malformed --exec-policy value.
+	ErrInvalidExecPolicy = errors.New("An exec policy must be of the form command=key:value[,key:value...], where command is the position of an --exec, counting from 1, and each key is retries, retry-backoff, retry-max-backoff, retry-jitter, or on-failure")
+
+	// ErrActionTimeout is returned by an ExecAction whose command ran past its timeout.
+	ErrActionTimeout = errors.New("The action timed out")
+
+	// ErrActionCanceled is returned by an ExecAction whose command was stopped
+	// because its context was canceled, e.g. when dms is shutting down.
+	ErrActionCanceled = errors.New("The action was canceled")
+
+	// ErrTriggerTimeout is the result of an action that was skipped because its
+	// trigger's timeout had already elapsed.
+	ErrTriggerTimeout = errors.New("The trigger timed out before the action could run")
+
+	// ErrTriggerCanceled is the result of an action that was skipped because its
+	// trigger had already been canceled.
+	ErrTriggerCanceled = errors.New("The trigger was canceled before the action could run")
 )
 
 // Action represents something that will trigger unless postponed.
@@ -25,37 +97,471 @@ type Action interface {
 	Run() error
 }
 
-// Trigger executes each action in sequence, providing a standard output
-// format for each action.
-func Trigger(l Logger, actions ...Action) {
-	for _, a := range actions {
-		l.Printf("[%s]", a.String())
-		if err := a.Run(); err != nil {
-			l.Printf("action error: %s", err)
+// Details carries information about why a switch triggered.  Exec actions
+// receive each detail as an environment variable named DMS_ followed by the
+// upper-cased key, e.g. DMS_SILENT.
+type Details map[string]string
+
+// String returns a human-readable representation of these details, with
+// keys in sorted order.
+func (d Details) String() string {
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	var o strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			o.WriteByte(' ')
 		}
+
+		fmt.Fprintf(&o, "[%s=%s]", k, d[k])
+	}
+
+	return o.String()
+}
+
+// Environ returns these details as environment variable assignments.
+func (d Details) Environ() []string {
+	env := make([]string, 0, len(d))
+	for k, v := range d {
+		env = append(env, fmt.Sprintf("DMS_%s=%s", strings.ToUpper(k), v))
+	}
+
+	sort.Strings(env)
+	return env
+}
+
+// DetailedAction is an optional interface for Actions that make use of the
+// Details of a trigger.  A trigger uses RunDetails in preference to Run.
+type DetailedAction interface {
+	Action
+	RunDetails(Details) error
+}
+
+// ContextAction is an optional interface for Actions that can be stopped while
+// they run.  A trigger uses RunContext in preference to RunDetails or Run.  When the
+// context is canceled or its deadline passes, RunContext should return promptly.
+// RunContext should not start at all if the context is already done, unless the
+// action must always run, as a ShutdownerAction must.
+type ContextAction interface {
+	Action
+	RunContext(context.Context, Details) error
+}
+
+// AsContextAction returns the given action as a ContextAction.  An action which
+// is not already a ContextAction is adapted so that it does not start once the
+// context is done.  Once started, such an action cannot be stopped, and runs
+// to completion.
+func AsContextAction(a Action) ContextAction {
+	if ca, ok := a.(ContextAction); ok {
+		return ca
+	}
+
+	return contextAction{Action: a}
+}
+
+// contextAction adapts an Action to the ContextAction interface.
+type contextAction struct {
+	Action
+}
+
+func (ca contextAction) RunContext(ctx context.Context, d Details) error {
+	if err := skipped(ctx); err != nil {
+		return err
+	}
+
+	if da, ok := ca.Action.(DetailedAction); ok {
+		return da.RunDetails(d)
 	}
+
+	return ca.Action.Run()
+}
+
+// skipped returns the result of an action that was not started because the
+// given context is done, or nil if the context is not done.  If the context was
+// canceled with a cause, such as ErrTriggerStopped, that cause is the result.
+func skipped(ctx context.Context) error {
+	switch err := ctx.Err(); {
+	case err == nil:
+		return nil
+
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrTriggerTimeout
+
+	case context.Cause(ctx) != err:
+		return context.Cause(ctx)
+
+	default:
+		return ErrTriggerCanceled
+	}
+}
+
+// runAction executes a single action with the given context, passing along
+// details if supported.
+func runAction(ctx context.Context, a Action, d Details) error {
+	return AsContextAction(a).RunContext(ctx, d)
+}
+
+// ExecAction is an Action that executes an external command.  A new process
+// is started each time this action runs.
+type ExecAction struct {
+	// Path is the command to execute.
+	Path string
+
+	// Args holds the command line arguments, including the command as Args[0].
+	Args []string
+
+	// Dir is the working directory of the command.
+	Dir string
+
+	// Stdout and Stderr receive the command's output.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Timeout is the optional time limit for each run of the command.  When it
+	// elapses, the command's process group is sent SIGTERM, and then SIGKILL if
+	// it is still running after KillGrace.  If nonpositive, there is no limit.
+	Timeout time.Duration
+
+	// KillGrace is the time between SIGTERM and SIGKILL for a command that timed
+	// out or was canceled.
+	//
+	// If nonpositive, DefaultKillGrace is used.
+	KillGrace time.Duration
+
+	// Retry is the optional policy for retrying this action when it fails.
+	Retry RetryPolicy
+
+	// Display describes this action in logs and status.  ParseExec sets this
+	// once, since resolving the command looks it up in the PATH.  If empty, the
+	// command is resolved each time this action is described.
+	Display string
 }
 
-// ParseExec parses the executable actions from a command line.
+// Command creates the *exec.Cmd for a single run of this action, adding
+// the given details to the environment.  Args[0] is not passed to the command,
+// so an action with no Args runs Path without arguments.
+func (ea *ExecAction) Command(d Details) *exec.Cmd {
+	var args []string
+	if len(ea.Args) > 1 {
+		args = ea.Args[1:]
+	}
+
+	cmd := exec.Command(ea.Path, args...)
+	cmd.Dir = ea.Dir
+	cmd.Stdout = ea.Stdout
+	cmd.Stderr = ea.Stderr
+	if len(d) > 0 {
+		cmd.Env = append(os.Environ(), d.Environ()...)
+	}
+
+	return cmd
+}
+
+var (
+	_ ContextAction  = (*ExecAction)(nil)
+	_ RetryingAction = (*ExecAction)(nil)
+)
+
+func (ea *ExecAction) String() string {
+	if len(ea.Display) > 0 {
+		return ea.Display
+	}
+
+	return ea.Command(nil).String()
+}
+
+func (ea *ExecAction) RetryPolicy() RetryPolicy {
+	return ea.Retry
+}
+
+func (ea *ExecAction) Run() error {
+	return ea.RunContext(context.Background(), nil)
+}
+
+func (ea *ExecAction) RunDetails(d Details) error {
+	return ea.RunContext(context.Background(), d)
+}
+
+// RunContext runs the command once.  If the context is done, or this action's
+// Timeout elapses, before the command exits, the command's process group is
+// sent SIGTERM, and then SIGKILL if any process in the group is still running
+// after KillGrace, even if the command itself has exited.  The command is not
+// started at all if the context is already done.
+func (ea *ExecAction) RunContext(ctx context.Context, d Details) error {
+	if err := skipped(ctx); err != nil {
+		return err
+	}
+
+	if ea.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ea.Timeout)
+		defer cancel()
+	}
+
+	cmd := ea.Command(d)
+	if ctx.Done() == nil {
+		// nothing can stop this command
+		return cmd.Run()
+	}
+
+	grace := ea.KillGrace
+	if grace <= 0 {
+		grace = DefaultKillGrace
+	}
+
+	// don't wait forever on output held open by a process that escaped the group
+	cmd.WaitDelay = grace
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+
+	case <-ctx.Done():
+	}
+
+	reason := ErrActionTimeout
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		reason = ErrActionCanceled
+	}
+
+	// the time left in a trigger's timeout is rarely a round number
+	elapsed := time.Since(start).Round(time.Millisecond)
+	terminateProcess(cmd)
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+		// the command exited, but processes it started may have ignored SIGTERM
+		if waitForGroup(cmd, timer.C) {
+			return fmt.Errorf("%w after %s, and was terminated", reason, elapsed)
+		}
+
+		killProcess(cmd)
+		return fmt.Errorf("%w after %s, and was terminated, and the rest of its process group was killed after a further %s", reason, elapsed, grace)
+
+	case <-timer.C:
+	}
+
+	killProcess(cmd)
+	<-done
+	return fmt.Errorf("%w after %s, and was killed after a further %s", reason, elapsed, grace)
+}
+
+// waitForGroup waits for every process in a started command's group to exit.
+// This function returns false if any process remains once expired is signaled.
+func waitForGroup(cmd *exec.Cmd, expired <-chan time.Time) bool {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for groupRunning(cmd) {
+		select {
+		case <-expired:
+			return false
+
+		case <-ticker.C:
+		}
+	}
+
+	return true
+}
+
+// parseCommand parses a single executable action, using the command line's
+// working directory, command options, timeouts, and retry policy.
+func parseCommand(e string, cl CommandLine) (Action, error) {
+	argv, err := ParseCommand(e, CommandOptions{Shell: cl.Shell, ExpandEnv: cl.ExpandEnv})
+	if err != nil {
+		return nil, err
+	}
+
+	ea := &ExecAction{
+		Path:      argv[0],
+		Args:      argv,
+		Dir:       cl.Dir,
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
+		Timeout:   cl.ActionTimeout,
+		KillGrace: cl.KillGrace,
+		Retry: RetryPolicy{
+			Retries:    cl.Retries,
+			Backoff:    cl.RetryBackoff,
+			MaxBackoff: cl.RetryMaxBackoff,
+			Jitter:     cl.RetryJitter,
+			OnFailure:  cl.OnFailure,
+		},
+	}
+
+	ea.Display = ea.Command(nil).String()
+	return ea, nil
+}
+
+// ParseExec parses the executable actions from a command line.  See ParseCommand
+// for the forms a command may take.
+//
+// Each --exec-policy value is of the form command=key:value[,key:value...], e.g.
+// 2=retries:3,on-failure:stop-remaining, and overrides the retry policy of a single
+// command.  Commands are counted from 1.  The keys are retries, retry-backoff,
+// retry-max-backoff, retry-jitter, and on-failure.
 func ParseExec(cl CommandLine) ([]Action, error) {
 	actions := make([]Action, 0, len(cl.Exec))
 
 	for _, e := range cl.Exec {
-		pieces := strings.Split(e, " ")
-		if len(pieces) == 0 || len(pieces[0]) == 0 {
-			return nil, ErrEmptyCommand
+		a, err := parseCommand(e, cl)
+		if err != nil {
+			return nil, err
 		}
 
-		cmd := exec.Command(pieces[0], pieces[1:]...)
-		cmd.Dir = cl.Dir
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		actions = append(actions, cmd)
+		actions = append(actions, a)
+	}
+
+	for _, v := range cl.ExecPolicy {
+		c, settings, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, ErrInvalidExecPolicy
+		}
+
+		p, err := strconv.Atoi(strings.TrimSpace(c))
+		switch {
+		case err != nil:
+			return nil, errors.Join(ErrInvalidExecPolicy, err)
+
+		case p < 1 || p > len(actions):
+			return nil, ErrInvalidExecPolicy
+		}
+
+		ea := actions[p-1].(*ExecAction)
+		if ea.Retry, err = ea.Retry.apply(settings); err != nil {
+			return nil, err
+		}
 	}
 
 	return actions, nil
 }
 
+// ParseRecover parses the recover actions from a command line.  If there
+// are no --recover commands, this function returns a nil slice.
+func ParseRecover(cl CommandLine) ([]Action, error) {
+	if len(cl.Recover) == 0 {
+		return nil, nil
+	}
+
+	return ParseExec(cl.withExec(cl.Recover))
+}
+
+// ParseTiers parses the escalation tiers from a command line.  Each --tier value
+// is of the form misses=command.  Commands with the same misses are grouped into
+// a single tier, and tiers are returned in ascending order of misses.
+func ParseTiers(cl CommandLine) ([]Tier, error) {
+	var tiers []Tier
+	for _, v := range cl.Tier {
+		m, e, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, ErrInvalidTier
+		}
+
+		misses, err := strconv.Atoi(m)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidTier, err)
+		}
+
+		a, err := parseCommand(e, cl)
+		if err != nil {
+			return nil, err
+		}
+
+		tiers = appendTier(tiers, misses, a)
+	}
+
+	return tiers, nil
+}
+
+// appendTier adds an action to the tier with the given misses, creating that tier
+// if necessary.  The tiers are kept in ascending order of misses.
+func appendTier(tiers []Tier, misses int, a Action) []Tier {
+	i := sort.Search(len(tiers), func(i int) bool { return tiers[i].Misses >= misses })
+	if i == len(tiers) || tiers[i].Misses != misses {
+		tiers = append(tiers, Tier{})
+		copy(tiers[i+1:], tiers[i:])
+		tiers[i] = Tier{Misses: misses}
+	}
+
+	tiers[i].Actions = append(tiers[i].Actions, a)
+	return tiers
+}
+
+// DryRunAction is an Action that logs what another Action would do, rather than
+// running it.  For an *ExecAction, the resolved command, working directory, and
+// the environment added by the trigger's Details are logged.  The inherited
+// environment is not logged, since it may hold secrets.
+type DryRunAction struct {
+	Logger Logger
+	Action Action
+}
+
+func (dra DryRunAction) String() string {
+	return dra.Action.String()
+}
+
+func (dra DryRunAction) Run() error {
+	return dra.RunDetails(nil)
+}
+
+func (dra DryRunAction) RunDetails(d Details) error {
+	ea, ok := dra.Action.(*ExecAction)
+	if !ok {
+		dra.Logger.Printf("dry run: would run %s", dra.Action)
+		return nil
+	}
+
+	cmd := ea.Command(d)
+	dir := cmd.Dir
+	if len(dir) == 0 {
+		dir, _ = os.Getwd()
+	}
+
+	env := "inherited"
+	if len(d) > 0 {
+		env += " " + strings.Join(d.Environ(), " ")
+	}
+
+	dra.Logger.Printf("dry run: would execute %s [dir=%s] [env=%s]", cmd, dir, env)
+
+	// report a command that could not be found, just as a real run would
+	return cmd.Err
+}
+
+// Barrier returns true if the wrapped action is a barrier, so that a dry run is
+// scheduled just as a real run would be.
+func (dra DryRunAction) Barrier() bool {
+	return barrier(dra.Action)
+}
+
+// DryRunActions wraps each of the given actions in a DryRunAction.
+func DryRunActions(l Logger, actions []Action) []Action {
+	if len(actions) == 0 {
+		return actions
+	}
+
+	dryRun := make([]Action, 0, len(actions))
+	for _, a := range actions {
+		dryRun = append(dryRun, DryRunAction{Logger: l, Action: a})
+	}
+
+	return dryRun
+}
+
 // ShutdownerAction allows an uber/fx.Shutdowner to be used as an Action.
 // This type is used to ensure that after trigger actions, the process exits.
 type ShutdownerAction struct {
@@ -70,11 +576,24 @@ func (sa ShutdownerAction) Run() error {
 	return sa.Shutdowner.Shutdown()
 }
 
+// Barrier returns true, so that the fx.App is shut down only after every other
+// action has finished.
+func (sa ShutdownerAction) Barrier() bool {
+	return true
+}
+
+// RunContext shuts down the fx.App, even if the context is done.  This ensures
+// that the process exits after a trigger that timed out or was canceled.
+func (sa ShutdownerAction) RunContext(context.Context, Details) error {
+	return sa.Run()
+}
+
 func provideActions() fx.Option {
 	return fx.Provide(
 		func(cl CommandLine, s fx.Shutdowner) (actions []Action, err error) {
 			actions, err = ParseExec(cl)
-			if err == nil {
+			if err == nil && !cl.Rearm {
+				// a re-arming switch keeps running after triggering
 				actions = append(actions, ShutdownerAction{Shutdowner: s})
 			}
 
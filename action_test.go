@@ -4,18 +4,34 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/fx"
 	"go.uber.org/fx/fxtest"
 )
 
+// processExited tests if the given process has exited, even if it has not yet
+// been reaped.
+func processExited(pid int) bool {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return errors.Is(err, os.ErrNotExist)
+	}
+
+	fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+	return len(fields) > 0 && fields[0] == "Z"
+}
+
 type ActionSuite struct {
 	suite.Suite
 
@@ -57,6 +73,19 @@ func (suite *ActionSuite) TestEmptyCommand() {
 		{
 			Exec: []string{"", "ls"},
 		},
+		{
+			Exec: []string{"  "},
+		},
+		{
+			Exec:  []string{"  "},
+			Shell: true,
+		},
+		{
+			Exec: []string{"[]"},
+		},
+		{
+			Exec: []string{`echo 'unterminated`},
+		},
 	}
 
 	suite.Run("ParseExec", func() {
@@ -118,6 +147,26 @@ func (suite *ActionSuite) TestValidCommands() {
 				{"echo", "another", "test"},
 			},
 		},
+		{
+			commandLine: CommandLine{
+				Exec: []string{`echo 'hello world'  "a,b"`, `["echo", "hello  world"]`},
+			},
+			expectedPieces: [][]string{
+				{"echo", "hello world", "a,b"},
+				{"echo", "hello  world"},
+			},
+		},
+		{
+			commandLine: CommandLine{
+				Exec:  []string{`echo "$DMS_SILENT" | wc -c`, `["echo", "$DMS_SILENT"]`},
+				Dir:   "/",
+				Shell: true,
+			},
+			expectedPieces: [][]string{
+				{"/bin/sh", "-c", `echo "$DMS_SILENT" | wc -c`},
+				{"echo", "$DMS_SILENT"},
+			},
+		},
 	}
 
 	suite.Run("ParseExec", func() {
@@ -129,7 +178,7 @@ func (suite *ActionSuite) TestValidCommands() {
 
 				for j, a := range actions {
 					suite.assertCmd(
-						a.(*exec.Cmd),
+						a.(*ExecAction).Command(nil),
 						testCase.commandLine.Dir,
 						testCase.expectedPieces[j],
 					)
@@ -159,7 +208,7 @@ func (suite *ActionSuite) TestValidCommands() {
 
 				for j := 0; j < len(actions)-1; j++ {
 					suite.assertCmd(
-						actions[j].(*exec.Cmd),
+						actions[j].(*ExecAction).Command(nil),
 						testCase.commandLine.Dir,
 						testCase.expectedPieces[j],
 					)
@@ -169,6 +218,65 @@ func (suite *ActionSuite) TestValidCommands() {
 	})
 }
 
+func (suite *ActionSuite) TestParseTiers() {
+	suite.Run("Valid", func() {
+		tiers, err := ParseTiers(CommandLine{
+			Tier: []string{"2=echo page", "1=echo warn", "2=echo another page"},
+			Dir:  "/tmp",
+		})
+
+		suite.Require().NoError(err)
+		suite.Require().Len(tiers, 2)
+
+		suite.Equal(1, tiers[0].Misses)
+		suite.Require().Len(tiers[0].Actions, 1)
+		suite.assertCmd(tiers[0].Actions[0].(*ExecAction).Command(nil), "/tmp", []string{"echo", "warn"})
+
+		suite.Equal(2, tiers[1].Misses)
+		suite.Require().Len(tiers[1].Actions, 2)
+		suite.assertCmd(tiers[1].Actions[0].(*ExecAction).Command(nil), "/tmp", []string{"echo", "page"})
+		suite.assertCmd(tiers[1].Actions[1].(*ExecAction).Command(nil), "/tmp", []string{"echo", "another", "page"})
+	})
+
+	suite.Run("Invalid", func() {
+		for _, value := range []string{"echo", "x=echo", "1="} {
+			suite.Run(value, func() {
+				tiers, err := ParseTiers(CommandLine{Tier: []string{value}})
+				suite.Empty(tiers)
+				suite.Error(err)
+			})
+		}
+	})
+}
+
+func (suite *ActionSuite) TestParseRecover() {
+	actions, err := ParseRecover(CommandLine{})
+	suite.NoError(err)
+	suite.Nil(actions)
+
+	actions, err = ParseRecover(CommandLine{Recover: []string{"echo recovered"}, Dir: "/tmp"})
+	suite.NoError(err)
+	suite.Require().Len(actions, 1)
+	suite.assertCmd(actions[0].(*ExecAction).Command(nil), "/tmp", []string{"echo", "recovered"})
+
+	_, err = ParseRecover(CommandLine{Recover: []string{""}})
+	suite.ErrorIs(err, ErrEmptyCommand)
+}
+
+func (suite *ActionSuite) TestRearmNoShutdowner() {
+	var actions []Action
+	fxtest.New(
+		suite.T(),
+		fx.Logger(DiscardLogger{}),
+		fx.Supply(CommandLine{Exec: []string{"echo test"}, Rearm: true}),
+		provideActions(),
+		fx.Populate(&actions),
+	)
+
+	suite.Require().Len(actions, 1)
+	suite.IsType(&ExecAction{}, actions[0])
+}
+
 func (suite *ActionSuite) TestSuccess() {
 	suite.shutdowner.On("Shutdown", []fx.ShutdownOption(nil)).Return(error(nil))
 	sa := ShutdownerAction{
@@ -192,6 +300,275 @@ func (suite *ActionSuite) TestError() {
 	suite.shutdowner.AssertExpectations(suite.T())
 }
 
+func (suite *ActionSuite) TestDetails() {
+	d := Details{"silent": "a,b", "misses": "2"}
+	suite.Equal("[misses=2] [silent=a,b]", d.String())
+	suite.Equal([]string{"DMS_MISSES=2", "DMS_SILENT=a,b"}, d.Environ())
+	suite.Empty(Details(nil).String())
+}
+
+func (suite *ActionSuite) TestExecAction() {
+	actions, err := ParseExec(CommandLine{Exec: []string{"sh -c exit"}, Dir: "/"})
+	suite.Require().NoError(err)
+	suite.Require().Len(actions, 1)
+
+	ea := actions[0].(*ExecAction)
+	suite.Contains(ea.String(), "sh -c exit")
+
+	// the command is resolved once, when it is parsed
+	suite.Equal(ea.Command(nil).String(), ea.Display)
+	ea.Display = "display"
+	suite.Equal("display", ea.String())
+
+	cmd := ea.Command(Details{"silent": "test"})
+	suite.Contains(cmd.Env, "DMS_SILENT=test")
+	suite.Equal("/", cmd.Dir)
+	suite.Nil(ea.Command(nil).Env)
+
+	// an ExecAction can be run more than once
+	suite.NoError(ea.Run())
+	suite.NoError(runAction(context.Background(), ea, Details{"silent": "test"}))
+}
+
+func (suite *ActionSuite) TestExecActionNoArgs() {
+	path, err := exec.LookPath("true")
+	suite.Require().NoError(err)
+
+	ea := &ExecAction{Path: path}
+	suite.Equal(path, ea.String())
+	suite.Equal([]string{path}, ea.Command(nil).Args)
+	suite.NoError(ea.Run())
+
+	// an action without a path fails rather than panicking
+	suite.Error((&ExecAction{}).Run())
+}
+
+func (suite *ActionSuite) TestShellExecAction() {
+	actions, err := ParseExec(CommandLine{Exec: []string{`test "$DMS_SILENT" = "a b"`}, Shell: true})
+	suite.Require().NoError(err)
+	suite.Require().Len(actions, 1)
+
+	// the shell sees the details in its environment
+	suite.NoError(runAction(context.Background(), actions[0], Details{SilentDetail: "a b"}))
+	suite.Error(runAction(context.Background(), actions[0], Details{SilentDetail: "a"}))
+}
+
+func (suite *ActionSuite) TestExecActionTimeout() {
+	suite.Run("Completed", func() {
+		ea := &ExecAction{Path: "true", Args: []string{"true"}, Timeout: time.Minute}
+		suite.NoError(ea.Run())
+	})
+
+	suite.Run("Terminated", func() {
+		var (
+			output bytes.Buffer
+			ea     = &ExecAction{
+				Path:   Shell,
+				Args:   []string{Shell, "-c", "sleep 10 & wait"},
+				Stdout: &output,
+
+				Timeout:   50 * time.Millisecond,
+				KillGrace: time.Second,
+			}
+
+			start = time.Now()
+		)
+
+		// the sleep holds the output open, so this only finishes promptly if
+		// the whole process group was signaled
+		err := ea.Run()
+		suite.ErrorIs(err, ErrActionTimeout)
+		suite.Contains(err.Error(), "terminated")
+		suite.Less(time.Since(start), DefaultKillGrace)
+	})
+
+	suite.Run("GroupKilled", func() {
+		var (
+			output bytes.Buffer
+			ea     = &ExecAction{
+				Path:   Shell,
+				Args:   []string{Shell, "-c", "(trap '' TERM; exec sleep 10 >/dev/null) & echo $!; wait"},
+				Stdout: &output,
+
+				Timeout:   50 * time.Millisecond,
+				KillGrace: 50 * time.Millisecond,
+			}
+		)
+
+		// the shell exits on SIGTERM, but the sleep ignores it and must be killed
+		err := ea.Run()
+		suite.ErrorIs(err, ErrActionTimeout)
+		suite.Contains(err.Error(), "process group was killed")
+
+		pid, err := strconv.Atoi(strings.TrimSpace(output.String()))
+		suite.Require().NoError(err)
+		suite.Eventually(func() bool { return processExited(pid) }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	suite.Run("Killed", func() {
+		var (
+			ea = &ExecAction{
+				Path: Shell,
+				Args: []string{Shell, "-c", "trap '' TERM; sleep 10"},
+
+				Timeout:   50 * time.Millisecond,
+				KillGrace: 50 * time.Millisecond,
+			}
+
+			start = time.Now()
+		)
+
+		err := ea.Run()
+		suite.ErrorIs(err, ErrActionTimeout)
+		suite.Contains(err.Error(), "killed")
+		suite.Less(time.Since(start), 5*time.Second)
+	})
+
+	suite.Run("Canceled", func() {
+		var (
+			ea          = &ExecAction{Path: "sleep", Args: []string{"sleep", "10"}, Timeout: time.Minute}
+			ctx, cancel = context.WithCancel(context.Background())
+			start       = time.Now()
+		)
+
+		time.AfterFunc(50*time.Millisecond, cancel)
+		err := ea.RunContext(ctx, nil)
+		suite.ErrorIs(err, ErrActionCanceled)
+		suite.Contains(err.Error(), "terminated")
+		suite.Less(time.Since(start), 5*time.Second)
+
+		// a command is not started once its context is done
+		suite.ErrorIs(ea.RunContext(ctx, nil), ErrTriggerCanceled)
+	})
+
+	suite.Run("Deadline", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		ea := &ExecAction{Path: "sleep", Args: []string{"sleep", "10"}, Timeout: time.Minute}
+		start := time.Now()
+		suite.ErrorIs(ea.RunContext(ctx, nil), ErrActionTimeout)
+		suite.Less(time.Since(start), 5*time.Second)
+
+		// the action's own timeout applies if it is shorter
+		ea.Timeout = 50 * time.Millisecond
+		suite.ErrorIs(ea.RunContext(context.Background(), nil), ErrActionTimeout)
+	})
+}
+
+func (suite *ActionSuite) TestAsContextAction() {
+	var (
+		ea = &ExecAction{Path: "true", Args: []string{"true"}}
+		da = &mockDetailedAction{mockAction{label: "detailed"}}
+		d  = Details{MissesDetail: "2"}
+
+		canceled, cancel = context.WithCancel(context.Background())
+	)
+
+	cancel()
+	suite.Same(ea, AsContextAction(ea))
+
+	ca := AsContextAction(da)
+	suite.Equal("detailed", ca.String())
+
+	// an adapted action is not started once its context is done
+	suite.ErrorIs(ca.RunContext(canceled, d), ErrTriggerCanceled)
+	da.AssertNotCalled(suite.T(), "RunDetails", d)
+
+	da.ExpectRunDetails(d).Return(nil).Once()
+	suite.NoError(ca.RunContext(context.Background(), d))
+	da.AssertExpectations(suite.T())
+}
+
+func (suite *ActionSuite) TestTriggerTimeout() {
+	var (
+		output   bytes.Buffer
+		results  []error
+		onResult = func(_ Action, err error) { results = append(results, err) }
+
+		actions = []Action{
+			&ExecAction{Path: "sleep", Args: []string{"sleep", "10"}},
+			&ExecAction{Path: "true", Args: []string{"true"}},
+			ShutdownerAction{Shutdowner: suite.shutdowner},
+		}
+	)
+
+	// the hung command is stopped, the next command is skipped, and shutdown still happens
+	suite.shutdowner.On("Shutdown", []fx.ShutdownOption(nil)).Return(error(nil)).Once()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	runSchedule(ctx, WriterLogger{Writer: &output}, nil, Schedule{}, onResult, actions...)
+	suite.Require().Len(results, 3)
+	suite.ErrorIs(results[0], ErrActionTimeout)
+	suite.ErrorIs(results[1], ErrTriggerTimeout)
+	suite.NoError(results[2])
+	suite.Contains(output.String(), "action error: The action timed out after ")
+	suite.Contains(output.String(), "and was terminated")
+	suite.shutdowner.AssertExpectations(suite.T())
+}
+
+func (suite *ActionSuite) TestDryRunAction() {
+	var (
+		output bytes.Buffer
+		logger = WriterLogger{Writer: &output}
+		marker = filepath.Join(suite.T().TempDir(), "ran")
+	)
+
+	suite.Run("Exec", func() {
+		output.Reset()
+		actions, err := ParseExec(CommandLine{Exec: []string{"touch " + marker}, Dir: "/"})
+		suite.Require().NoError(err)
+
+		dra := DryRunAction{Logger: logger, Action: actions[0]}
+		suite.Equal(actions[0].String(), dra.String())
+		suite.NoError(runAction(context.Background(), dra, Details{MissesDetail: "2"}))
+		suite.NoFileExists(marker)
+
+		suite.Contains(output.String(), "dry run: would execute ")
+		suite.Contains(output.String(), "touch "+marker)
+		suite.Contains(output.String(), "[dir=/] [env=inherited DMS_MISSES=2]")
+	})
+
+	suite.Run("CurrentDirectory", func() {
+		output.Reset()
+		actions, err := ParseExec(CommandLine{Exec: []string{"touch " + marker}})
+		suite.Require().NoError(err)
+
+		wd, err := os.Getwd()
+		suite.Require().NoError(err)
+
+		suite.NoError(DryRunAction{Logger: logger, Action: actions[0]}.Run())
+		suite.NoFileExists(marker)
+		suite.Contains(output.String(), "[dir="+wd+"] [env=inherited]")
+	})
+
+	suite.Run("NoSuchCommand", func() {
+		output.Reset()
+		actions, err := ParseExec(CommandLine{Exec: []string{"dms-no-such-command"}})
+		suite.Require().NoError(err)
+		suite.Error(DryRunAction{Logger: logger, Action: actions[0]}.Run())
+	})
+
+	suite.Run("Shutdowner", func() {
+		output.Reset()
+		dra := DryRunAction{Logger: logger, Action: ShutdownerAction{Shutdowner: suite.shutdowner}}
+		suite.NoError(dra.Run())
+		suite.Equal("dry run: would run Shutdowner\n", output.String())
+		suite.shutdowner.AssertNotCalled(suite.T(), "Shutdown")
+	})
+}
+
+func (suite *ActionSuite) TestDryRunActions() {
+	suite.Empty(DryRunActions(DiscardLogger{}, nil))
+
+	actions := newMockActions(2).actions()
+	dryRun := DryRunActions(DiscardLogger{}, actions)
+	suite.Require().Len(dryRun, 2)
+	for i := range actions {
+		suite.Equal(DryRunAction{Logger: DiscardLogger{}, Action: actions[i]}, dryRun[i])
+	}
+}
+
 func TestAction(t *testing.T) {
 	suite.Run(t, new(ActionSuite))
 }
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.uber.org/fx"
+)
+
+var (
+	// ErrInvalidAdminToken is returned by NewAuthenticator when an admin token
+	// is not of the form name=token.
+	ErrInvalidAdminToken = errors.New("An admin token must be given as name=token")
+)
+
+// adminToken is a single named bearer token.
+type adminToken struct {
+	name  string
+	token []byte
+}
+
+// Authenticator verifies the bearer tokens presented to administrative
+// endpoints, such as /trigger.  Each token has a name, which identifies
+// who made a request.
+type Authenticator struct {
+	tokens []adminToken
+}
+
+// NewAuthenticator creates an Authenticator from name=token pairs.  If no
+// pairs are supplied, this function returns a nil Authenticator.
+func NewAuthenticator(pairs ...string) (*Authenticator, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	a := new(Authenticator)
+	for _, p := range pairs {
+		name, token, ok := strings.Cut(p, "=")
+		name = strings.TrimSpace(name)
+		if !ok || len(name) == 0 || len(token) == 0 {
+			return nil, ErrInvalidAdminToken
+		}
+
+		a.tokens = append(a.tokens, adminToken{name: name, token: []byte(token)})
+	}
+
+	return a, nil
+}
+
+// Authenticate returns the name of the token carried by the request's
+// Authorization header.  This method returns false if the request has
+// no bearer token or if the token is not known.
+func (a *Authenticator) Authenticate(request *http.Request) (name string, ok bool) {
+	scheme, token, found := strings.Cut(request.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return
+	}
+
+	// compare against every token, so that timing reveals nothing about which matched
+	presented := []byte(strings.TrimSpace(token))
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare(presented, t.token) == 1 && !ok {
+			name, ok = t.name, true
+		}
+	}
+
+	return
+}
+
+// principalKey is the context key for the name of an authenticated caller.
+type principalKey struct{}
+
+// Principal returns the name of the caller authenticated by an AuthHandler.
+func Principal(ctx context.Context) (name string, ok bool) {
+	name, ok = ctx.Value(principalKey{}).(string)
+	return
+}
+
+// AuthHandler decorates a Handler so that it only receives authenticated
+// requests.  The caller's name is available to the Handler via Principal.
+// Unauthenticated requests receive http.StatusUnauthorized.
+type AuthHandler struct {
+	Logger        Logger
+	Authenticator *Authenticator
+	Handler       http.Handler
+}
+
+func (ah AuthHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	name, ok := ah.Authenticator.Authenticate(request)
+	if !ok {
+		ah.Logger.Printf("unauthorized %s %s [remoteaddr=%s]", request.Method, request.URL.Path, request.RemoteAddr)
+		response.Header().Set("WWW-Authenticate", "Bearer")
+		response.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	ah.Handler.ServeHTTP(
+		response,
+		request.WithContext(context.WithValue(request.Context(), principalKey{}, name)),
+	)
+}
+
+// provideAuthenticator creates an fx.Option that provides the *Authenticator
+// for the --admin-token command line options.  The *Authenticator is nil when
+// no admin tokens were supplied, in which case administrative endpoints are
+// not available.
+func provideAuthenticator() fx.Option {
+	return fx.Provide(
+		func(cl CommandLine) (*Authenticator, error) {
+			return NewAuthenticator(cl.AdminToken...)
+		},
+	)
+}
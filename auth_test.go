@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AuthSuite struct {
+	DMSSuite
+}
+
+func (suite *AuthSuite) newRequest(authorization string) *http.Request {
+	request := httptest.NewRequest("POST", TriggerPath, nil)
+	if len(authorization) > 0 {
+		request.Header.Set("Authorization", authorization)
+	}
+
+	return request
+}
+
+func (suite *AuthSuite) TestNewAuthenticator() {
+	suite.Run("None", func() {
+		a, err := NewAuthenticator()
+		suite.NoError(err)
+		suite.Nil(a)
+	})
+
+	for _, invalid := range []string{"", "secret", "=secret", "ops=", " =secret"} {
+		suite.Run("Invalid/"+invalid, func() {
+			a, err := NewAuthenticator("ops=secret", invalid)
+			suite.ErrorIs(err, ErrInvalidAdminToken)
+			suite.Nil(a)
+		})
+	}
+}
+
+func (suite *AuthSuite) TestAuthenticate() {
+	a, err := NewAuthenticator("ops=secret", "oncall=another=token")
+	suite.Require().NoError(err)
+	suite.Require().NotNil(a)
+
+	testData := []struct {
+		authorization string
+		expectedName  string
+		expectedOK    bool
+	}{
+		{"", "", false},
+		{"secret", "", false},
+		{"Basic secret", "", false},
+		{"Bearer wrong", "", false},
+		{"Bearer ", "", false},
+		{"Bearer secret", "ops", true},
+		{"bearer secret", "ops", true},
+		{"Bearer another=token", "oncall", true},
+	}
+
+	for _, testCase := range testData {
+		suite.Run(testCase.authorization, func() {
+			name, ok := a.Authenticate(suite.newRequest(testCase.authorization))
+			suite.Equal(testCase.expectedName, name)
+			suite.Equal(testCase.expectedOK, ok)
+		})
+	}
+}
+
+func (suite *AuthSuite) TestAuthHandler() {
+	a, err := NewAuthenticator("ops=secret")
+	suite.Require().NoError(err)
+
+	var principal string
+	ah := AuthHandler{
+		Logger:        suite.logger,
+		Authenticator: a,
+		Handler: http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			principal, _ = Principal(request.Context())
+			response.WriteHeader(http.StatusOK)
+		}),
+	}
+
+	suite.Run("Unauthorized", func() {
+		response := httptest.NewRecorder()
+		ah.ServeHTTP(response, suite.newRequest("Bearer wrong"))
+		suite.Equal(http.StatusUnauthorized, response.Code)
+		suite.Equal("Bearer", response.Header().Get("WWW-Authenticate"))
+		suite.Empty(principal)
+	})
+
+	suite.Run("Authorized", func() {
+		response := httptest.NewRecorder()
+		ah.ServeHTTP(response, suite.newRequest("Bearer secret"))
+		suite.Equal(http.StatusOK, response.Code)
+		suite.Equal("ops", principal)
+	})
+
+	_, ok := Principal(suite.newRequest("").Context())
+	suite.False(ok)
+}
+
+func TestAuth(t *testing.T) {
+	suite.Run(t, new(AuthSuite))
+}
@@ -0,0 +1,230 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Shell is the shell used to run commands in shell mode.
+const Shell = "/bin/sh"
+
+var (
+	// ErrInvalidCommand is returned by ParseCommand when a command has unbalanced
+	// quotes, ends in an escape, or is a malformed JSON array.
+	ErrInvalidCommand = errors.New("A command must be shell words or a JSON array of strings")
+)
+
+// CommandOptions controls how ParseCommand interprets a command.
+type CommandOptions struct {
+	// Shell indicates that a command is run by Shell, via sh -c, rather than
+	// being split into words.  A JSON array of strings is never run by the shell,
+	// but any other command that starts with [, e.g. [ -f /tmp/x ], is.
+	Shell bool
+
+	// ExpandEnv indicates that $NAME and ${NAME} are replaced with the values
+	// of environment variables when a command is split into words.  This is
+	// done once, when the command is parsed.
+	ExpandEnv bool
+}
+
+// ParseCommand parses a command into the argv used to execute it.  A command
+// that starts with [ is a JSON array of strings, which is used as is.  In shell
+// mode, a command that starts with [ but is not a JSON array of strings is run by
+// the shell instead.  Otherwise, the command is either run by the shell or split
+// into words by the same rules that a POSIX shell uses for quotes and escapes.
+//
+// This function returns ErrEmptyCommand if there is no command to run.
+func ParseCommand(e string, o CommandOptions) (argv []string, err error) {
+	isJSON := strings.HasPrefix(strings.TrimSpace(e), "[")
+	if isJSON && o.Shell {
+		// a shell command such as [ -f /tmp/x ] && echo hi is not JSON
+		isJSON = json.Unmarshal([]byte(e), new([]string)) == nil
+	}
+
+	switch {
+	case isJSON:
+		if err = json.Unmarshal([]byte(e), &argv); err != nil {
+			return nil, errors.Join(ErrInvalidCommand, err)
+		}
+
+	case o.Shell && len(strings.TrimSpace(e)) > 0:
+		argv = []string{Shell, "-c", e}
+
+	default:
+		var expand func(string) string
+		if o.ExpandEnv {
+			expand = os.Getenv
+		}
+
+		if argv, err = splitWords(e, expand); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(argv) == 0 || len(argv[0]) == 0 {
+		return nil, ErrEmptyCommand
+	}
+
+	return
+}
+
+// wordSplitter holds the state of splitting a single command into words.
+type wordSplitter struct {
+	expand func(string) string
+
+	words []string
+	word  strings.Builder
+
+	// inWord is true when a word has been started, even if it is empty, e.g. ''
+	inWord bool
+}
+
+// end finishes the current word, if any.
+func (ws *wordSplitter) end() {
+	if ws.inWord {
+		ws.words = append(ws.words, ws.word.String())
+		ws.word.Reset()
+		ws.inWord = false
+	}
+}
+
+// add appends text to the current word, starting one if necessary.
+func (ws *wordSplitter) add(s string) {
+	ws.word.WriteString(s)
+	ws.inWord = true
+}
+
+// variable handles a $ at the start of the given text, returning the number of
+// bytes consumed.  Without an expand function, or when no variable name follows,
+// the $ is literal.
+func (ws *wordSplitter) variable(s string) int {
+	if ws.expand != nil {
+		if name, n := variableName(s[1:]); n > 0 {
+			ws.add(ws.expand(name))
+			return n + 1
+		}
+	}
+
+	ws.add("$")
+	return 1
+}
+
+// variableName returns the name at the start of the text following a $, along
+// with the number of bytes it occupies.  Both NAME and {NAME} are allowed.
+func variableName(s string) (string, int) {
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end < 2 || nameLength(s[1:end]) != end-1 {
+			return "", 0
+		}
+
+		return s[1:end], end + 1
+	}
+
+	n := nameLength(s)
+	return s[:n], n
+}
+
+// nameLength returns the length of the environment variable name at the start of s.
+func nameLength(s string) (n int) {
+	for n < len(s) {
+		c := s[n]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (n == 0 || c < '0' || c > '9') {
+			break
+		}
+
+		n++
+	}
+
+	return
+}
+
+// splitWords splits a command into words using POSIX shell quoting.  Words are
+// separated by unquoted whitespace.  A backslash escapes the next character,
+// single quotes preserve everything up to the closing quote, and within double
+// quotes a backslash only escapes $, `, ", \, or a newline.  If expand is not nil,
+// it supplies the values of $NAME and ${NAME} outside of single quotes.  Unlike a
+// shell, the value of a variable is never split into multiple words.
+func splitWords(s string, expand func(string) string) ([]string, error) {
+	ws := wordSplitter{expand: expand}
+	for i := 0; i < len(s); {
+		switch c := s[i]; c {
+		case ' ', '\t', '\n':
+			ws.end()
+			i++
+
+		case '\\':
+			switch {
+			case i+1 == len(s):
+				return nil, fmt.Errorf("%w: trailing backslash", ErrInvalidCommand)
+
+			case s[i+1] != '\n':
+				// a backslash and a newline are a line continuation, and are removed
+				ws.add(s[i+1 : i+2])
+			}
+
+			i += 2
+
+		case '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated single quote", ErrInvalidCommand)
+			}
+
+			ws.add(s[i+1 : i+1+end])
+			i += end + 2
+
+		case '"':
+			n, err := ws.doubleQuoted(s[i+1:])
+			if err != nil {
+				return nil, err
+			}
+
+			i += n + 1
+
+		case '$':
+			i += ws.variable(s[i:])
+
+		default:
+			ws.add(s[i : i+1])
+			i++
+		}
+	}
+
+	ws.end()
+	return ws.words, nil
+}
+
+// doubleQuoted handles the text following an opening double quote, returning the
+// number of bytes consumed including the closing quote.
+func (ws *wordSplitter) doubleQuoted(s string) (int, error) {
+	ws.add("")
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == '"':
+			return i + 1, nil
+
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0:
+			if s[i+1] != '\n' {
+				ws.add(s[i+1 : i+2])
+			}
+
+			i += 2
+
+		case c == '$':
+			i += ws.variable(s[i:])
+
+		default:
+			ws.add(s[i : i+1])
+			i++
+		}
+	}
+
+	return 0, fmt.Errorf("%w: unterminated double quote", ErrInvalidCommand)
+}
@@ -4,7 +4,10 @@
 package main
 
 import (
+	"errors"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/alecthomas/kong"
@@ -12,20 +15,135 @@ import (
 )
 
 type CommandLine struct {
-	Exec   []string      `name:"exec" short:"e" required:"" help:"one or more commands to execute when the switch triggers"`
-	Dir    string        `name:"dir" short:"d" optional:"" help:"the working directory for all commands"`
-	HTTP   string        `name:"http" short:"h" default:":8080" help:"the HTTP listen address or port"`
-	TTL    time.Duration `name:"ttl" short:"t" default:"1m" help:"the maximum interval for TTL updates to keep the switch open"`
-	Misses int           `name:"misses" short:"m" default:"1" help:"the maximum number of missed updates allowed before the switch closes"`
-	Debug  bool          `name:"debug" default:"false" help:"produce debug logging"`
+	Exec     []string      `name:"exec" short:"e" required:"" sep:"none" help:"a command to execute when the switch triggers, as shell words or a JSON array.  may be repeated"`
+	Dir      string        `name:"dir" short:"d" optional:"" help:"the working directory for all commands"`
+	HTTP     string        `name:"http" short:"h" default:":8080" help:"the HTTP listen address or port"`
+	TTL      time.Duration `name:"ttl" short:"t" default:"1m" help:"the maximum interval for TTL updates to keep the switch open"`
+	MinTTL   time.Duration `name:"min-ttl" default:"1s" help:"the minimum ttl that a single postpone may request.  shorter requests are rejected"`
+	MaxTTL   time.Duration `name:"max-ttl" default:"1h" help:"the maximum ttl that a single postpone may request"`
+	MaxPause time.Duration `name:"max-pause" default:"24h" help:"the maximum duration of a pause.  longer pauses are rejected"`
+	Misses   int           `name:"misses" short:"m" default:"1" help:"the maximum number of missed updates allowed before the switch closes"`
+	Source   []string      `name:"source" short:"s" optional:"" help:"an expected postpone source, as name[:ttl[:misses[:weight]]].  when set, the switch triggers as soon as any expected source goes silent"`
+	Quorum   int           `name:"quorum" short:"q" optional:"" help:"the weighted number of distinct sources that must postpone within the TTL.  when set, the switch triggers only when the quorum is missed"`
+	Grace    time.Duration `name:"grace" optional:"" help:"an initial period after startup during which misses are not counted"`
+	ArmAfter int           `name:"arm-after" optional:"" help:"hold the switch unarmed until this many consecutive postpones have been received, or until the grace period ends"`
+	Window   []string      `name:"window" optional:"" sep:"none" help:"a recurring maintenance window, as cron;duration[;zone], during which misses are not counted"`
+	Tier     []string      `name:"tier" optional:"" sep:"none" help:"an escalation command, as misses=command, which runs when the switch first reaches that many misses"`
+	Recover  []string      `name:"recover" optional:"" sep:"none" help:"a command to execute when postpones resume after one or more misses.  may be repeated"`
+	Rearm    bool          `name:"rearm" default:"false" help:"re-arm the switch after triggering instead of exiting.  a tripped switch is re-armed by a postpone or a PUT to /rearm"`
+
+	Shell     bool `name:"shell" default:"false" help:"run each command with /bin/sh -c rather than splitting it into words"`
+	ExpandEnv bool `name:"expand-env" default:"false" help:"replace $$NAME and $${NAME} in commands with environment variables when the commands are parsed"`
+
+	ActionTimeout  time.Duration `name:"action-timeout" optional:"" help:"the maximum time each command may run.  a command that runs longer is sent SIGTERM, then SIGKILL after --kill-grace"`
+	TriggerTimeout time.Duration `name:"trigger-timeout" optional:"" help:"the maximum time all of a trigger's commands may run, after which any remaining commands are skipped"`
+	KillGrace      time.Duration `name:"kill-grace" default:"5s" help:"the time between SIGTERM and SIGKILL for a command that timed out"`
+
+	Retries         int           `name:"retries" optional:"" help:"the number of times to retry a command that fails"`
+	RetryBackoff    time.Duration `name:"retry-backoff" default:"1s" help:"the delay before the first retry of a command, which doubles for each further retry"`
+	RetryMaxBackoff time.Duration `name:"retry-max-backoff" default:"1m" help:"the maximum delay between retries of a command"`
+	RetryJitter     float64       `name:"retry-jitter" default:"0.2" help:"the fraction, from 0 to 1, by which each retry delay is randomly varied"`
+	OnFailure       string        `name:"on-failure" default:"continue" enum:"continue,stop-remaining,abort-and-keep-running" help:"what a trigger does once a command has failed and exhausted its retries: continue, stop-remaining, which skips the remaining commands, or abort-and-keep-running, which also keeps dms running"`
+	ExecPolicy      []string      `name:"exec-policy" optional:"" sep:"none" help:"a retry and failure policy for a single --exec command, as command=key:value[,key:value...], e.g. 2=retries:3,on-failure:stop-remaining.  the keys are retries, retry-backoff, retry-max-backoff, retry-jitter, and on-failure, and override those flags for that command"`
+
+	Parallel int      `name:"parallel" default:"1" help:"the maximum number of commands that a trigger runs at once.  with 1, commands run one at a time, in order"`
+	After    []string `name:"after" optional:"" sep:"none" help:"with --parallel, a dependency between --exec commands, as command=command[,command...], e.g. 3=1,2 to run the third command once the first and second have finished"`
+
+	MinPostpones int `name:"min-postpones" optional:"" help:"a miss budget.  when set, the switch triggers as soon as fewer than this many of the last --budget-window intervals had a postpone, instead of on --misses consecutive misses"`
+	BudgetWindow int `name:"budget-window" default:"10" help:"the number of TTL intervals over which --min-postpones applies"`
+
+	Cooldown      time.Duration `name:"cooldown" optional:"" help:"with --rearm, the minimum time after tripping before a postpone re-arms the switch"`
+	MaxTriggers   int           `name:"max-triggers" optional:"" help:"with --rearm, the maximum number of times actions run within the trigger window"`
+	TriggerWindow time.Duration `name:"trigger-window" default:"1h" help:"the window for --max-triggers"`
+
+	StateFile  string `name:"state-file" optional:"" type:"path" help:"a file in which to persist the switch's state, so that a restart does not reset the countdown"`
+	MarkerFile string `name:"marker-file" optional:"" type:"path" help:"a file written before the switch triggers.  while it exists, the switch will not arm.  clear it with the clear-marker command"`
+
+	Rule      []string `name:"rule" optional:"" sep:"none" help:"a rule that each heartbeat must pass to postpone the switch, as field operator value, e.g. 'queue_depth < 1000'.  the field is status or the name of a metric"`
+	Unhealthy string   `name:"unhealthy" default:"miss" enum:"miss,trigger" help:"what a heartbeat that fails a --rule does: miss, which leaves the deadline to pass, or trigger, which triggers the switch immediately"`
+
+	AdminToken []string `name:"admin-token" optional:"" env:"DMS_ADMIN_TOKEN" help:"a bearer token for the administrative endpoints, such as /trigger, as name=token.  the name identifies the caller in the log"`
+
+	DryRun bool `name:"dry-run" default:"false" help:"log what each action would do instead of running it.  dms keeps running, and the switch re-arms, after it triggers"`
+
+	Config string `name:"config" short:"c" optional:"" type:"existingfile" help:"a JSON file describing additional named switches"`
+	Debug  bool   `name:"debug" default:"false" help:"produce debug logging"`
+}
+
+var (
+	// ErrInvalidSource is returned by ParseSource to indicate a malformed --source value.
+	ErrInvalidSource = errors.New("A source must be of the form name[:ttl[:misses[:weight]]]")
+)
+
+// ParseSource parses an expected source from the command line.  The value is of the
+// form name[:ttl[:misses[:weight]]], where the optional ttl and misses override the switch's
+// values and the optional weight is used for a quorum.
+func ParseSource(v string) (sc SourceConfig, err error) {
+	pieces := strings.Split(v, ":")
+	if len(pieces) > 4 || len(pieces[0]) == 0 {
+		return SourceConfig{}, ErrInvalidSource
+	}
+
+	sc.Name = pieces[0]
+	if len(pieces) > 1 && len(pieces[1]) > 0 {
+		sc.TTL, err = time.ParseDuration(pieces[1])
+	}
+
+	if err == nil && len(pieces) > 2 && len(pieces[2]) > 0 {
+		sc.MaxMisses, err = strconv.Atoi(pieces[2])
+	}
+
+	if err == nil && len(pieces) > 3 && len(pieces[3]) > 0 {
+		sc.Weight, err = strconv.Atoi(pieces[3])
+	}
+
+	if err != nil {
+		return SourceConfig{}, errors.Join(ErrInvalidSource, err)
+	}
+
+	return
+}
+
+// withExec returns a copy of this command line with the given commands, so that
+// other commands can be parsed with this command line's directory and options.
+// Each --exec-policy applies to an --exec command, so none are kept.
+func (cl CommandLine) withExec(exec []string) CommandLine {
+	cl.Exec = exec
+	cl.ExecPolicy = nil
+	return cl
+}
+
+// ParseSources parses each of the --source values from a command line.
+func ParseSources(cl CommandLine) ([]SourceConfig, error) {
+	var sources []SourceConfig
+	for _, v := range cl.Source {
+		sc, err := ParseSource(v)
+		if err != nil {
+			return nil, err
+		}
+
+		sources = append(sources, sc)
+	}
+
+	return sources, nil
+}
+
+// CLI is the complete dms command line.  Running a switch is the default command,
+// so its flags may be given without naming the command.
+type CLI struct {
+	Run         CommandLine        `cmd:"" default:"withargs" help:"run the dead man's switch.  this is the default command"`
+	ClearMarker ClearMarkerCommand `cmd:"" name:"clear-marker" help:"clear a trigger marker, so that a switch can arm again"`
 }
 
-func parseCommandLine(args []string) fx.Option {
+// parseCommandLine parses the dms command line.  The given run options are only
+// used when the command line runs a switch.
+func parseCommandLine(args []string, run ...fx.Option) fx.Option {
 	var (
 		options []fx.Option
-		cl      CommandLine
+		cli     CLI
+		ctx     *kong.Context
 		k, err  = kong.New(
-			&cl,
+			&cli,
 			kong.Description(
 				"A dead man's switch which invokes one or more actions unless postponed on regular intervals.  To postpone the action(s), issue an HTTP PUT to /postpone, with no body, to the configured HTTP address.",
 			),
@@ -33,10 +151,17 @@ func parseCommandLine(args []string) fx.Option {
 	)
 
 	if err == nil {
-		_, err = k.Parse(args)
+		ctx, err = k.Parse(args)
 	}
 
-	if err == nil {
+	if err == nil && strings.HasPrefix(ctx.Command(), "clear-marker") {
+		options = append(options,
+			fx.Logger(DiscardLogger{}),
+			fx.Supply(cli.ClearMarker),
+			provideClearMarker(),
+		)
+	} else if err == nil {
+		cl := cli.Run
 		var debug Logger
 		if cl.Debug {
 			debug = WriterLogger{Writer: os.Stdout}
@@ -48,6 +173,8 @@ func parseCommandLine(args []string) fx.Option {
 			fx.Logger(debug),
 			fx.Supply(cl),
 		)
+
+		options = append(options, run...)
 	}
 
 	if err != nil {
@@ -5,6 +5,7 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/fx"
@@ -55,6 +56,48 @@ func (suite *CommandLineSuite) TestDebug() {
 	app.RequireStop()
 }
 
+func (suite *CommandLineSuite) TestParseSource() {
+	suite.Run("Valid", func() {
+		testData := []struct {
+			value    string
+			expected SourceConfig
+		}{
+			{"db", SourceConfig{Name: "db"}},
+			{"db:30s", SourceConfig{Name: "db", TTL: 30 * time.Second}},
+			{"db:30s:2", SourceConfig{Name: "db", TTL: 30 * time.Second, MaxMisses: 2}},
+			{"db::2", SourceConfig{Name: "db", MaxMisses: 2}},
+			{"db:::3", SourceConfig{Name: "db", Weight: 3}},
+			{"db:30s:2:3", SourceConfig{Name: "db", TTL: 30 * time.Second, MaxMisses: 2, Weight: 3}},
+		}
+
+		for _, testCase := range testData {
+			suite.Run(testCase.value, func() {
+				sc, err := ParseSource(testCase.value)
+				suite.NoError(err)
+				suite.Equal(testCase.expected, sc)
+			})
+		}
+	})
+
+	suite.Run("Invalid", func() {
+		for _, value := range []string{"", ":30s", "db:nosuch", "db:30s:nosuch", "db:30s:2:nosuch", "db:30s:2:3:extra"} {
+			suite.Run(value, func() {
+				_, err := ParseSource(value)
+				suite.ErrorIs(err, ErrInvalidSource)
+			})
+		}
+	})
+
+	suite.Run("ParseSources", func() {
+		sources, err := ParseSources(CommandLine{Source: []string{"a", "b:1m"}})
+		suite.NoError(err)
+		suite.Equal([]SourceConfig{{Name: "a"}, {Name: "b", TTL: time.Minute}}, sources)
+
+		_, err = ParseSources(CommandLine{Source: []string{"a", ""}})
+		suite.ErrorIs(err, ErrInvalidSource)
+	})
+}
+
 func TestCommandLine(t *testing.T) {
 	suite.Run(t, new(CommandLineSuite))
 }
@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CommandSuite struct {
+	DMSSuite
+}
+
+func (suite *CommandSuite) TestSplitWords() {
+	testData := []struct {
+		command  string
+		expected []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"echo hello", []string{"echo", "hello"}},
+		{"  echo \t hello\n world  ", []string{"echo", "hello", "world"}},
+		{`echo 'hello world'`, []string{"echo", "hello world"}},
+		{`echo "hello world"`, []string{"echo", "hello world"}},
+		{`echo hello\ world`, []string{"echo", "hello world"}},
+		{`echo '' ""`, []string{"echo", "", ""}},
+		{`echo a'b'"c"`, []string{"echo", "abc"}},
+		{`echo 'a \ "b" $HOME'`, []string{"echo", `a \ "b" $HOME`}},
+		{`echo "a \" \\ \$ \a 'b'"`, []string{"echo", `a " \ $ \a 'b'`}},
+		{"echo a\\\nb", []string{"echo", "ab"}},
+		{`echo $HOME ${HOME}`, []string{"echo", "$HOME", "${HOME}"}},
+	}
+
+	for _, testCase := range testData {
+		words, err := splitWords(testCase.command, nil)
+		suite.NoError(err, testCase.command)
+		suite.Equal(testCase.expected, words, testCase.command)
+	}
+}
+
+func (suite *CommandSuite) TestSplitWordsExpand() {
+	expand := func(name string) string {
+		return map[string]string{"NAME": "a b", "EMPTY": "", "X1": "x"}[name]
+	}
+
+	testData := []struct {
+		command  string
+		expected []string
+	}{
+		{`echo $NAME`, []string{"echo", "a b"}},
+		{`echo "${NAME}"s`, []string{"echo", "a bs"}},
+		{`echo $X1$X1`, []string{"echo", "xx"}},
+		{`echo '$NAME'`, []string{"echo", "$NAME"}},
+		{`echo \$NAME`, []string{"echo", "$NAME"}},
+		{`echo $EMPTY`, []string{"echo", ""}},
+		{`echo $ $1 ${} ${1} ${NAME`, []string{"echo", "$", "$1", "${}", "${1}", "${NAME"}},
+	}
+
+	for _, testCase := range testData {
+		words, err := splitWords(testCase.command, expand)
+		suite.NoError(err, testCase.command)
+		suite.Equal(testCase.expected, words, testCase.command)
+	}
+}
+
+func (suite *CommandSuite) TestSplitWordsInvalid() {
+	for _, command := range []string{
+		`echo 'hello`,
+		`echo "hello`,
+		`echo "hello\"`,
+		`echo hello\`,
+	} {
+		_, err := splitWords(command, nil)
+		suite.ErrorIs(err, ErrInvalidCommand, command)
+	}
+}
+
+func (suite *CommandSuite) TestParseCommand() {
+	suite.Run("Words", func() {
+		argv, err := ParseCommand(`echo "hello world"`, CommandOptions{})
+		suite.NoError(err)
+		suite.Equal([]string{"echo", "hello world"}, argv)
+	})
+
+	suite.Run("ExpandEnv", func() {
+		suite.T().Setenv("DMS_COMMAND_TEST", "hello world")
+		argv, err := ParseCommand(`echo $DMS_COMMAND_TEST`, CommandOptions{ExpandEnv: true})
+		suite.NoError(err)
+		suite.Equal([]string{"echo", "hello world"}, argv)
+	})
+
+	suite.Run("Shell", func() {
+		argv, err := ParseCommand(`echo "$HOME" | wc -c`, CommandOptions{Shell: true, ExpandEnv: true})
+		suite.NoError(err)
+		suite.Equal([]string{Shell, "-c", `echo "$HOME" | wc -c`}, argv)
+
+		// a shell command may start with [ without being a JSON array
+		argv, err = ParseCommand(`[ -f /tmp/x ] && echo hi`, CommandOptions{Shell: true})
+		suite.NoError(err)
+		suite.Equal([]string{Shell, "-c", `[ -f /tmp/x ] && echo hi`}, argv)
+
+		argv, err = ParseCommand(`["echo", 1]`, CommandOptions{Shell: true})
+		suite.NoError(err)
+		suite.Equal([]string{Shell, "-c", `["echo", 1]`}, argv)
+	})
+
+	suite.Run("JSON", func() {
+		argv, err := ParseCommand(` ["echo", "it's", "$HOME"]`, CommandOptions{Shell: true, ExpandEnv: true})
+		suite.NoError(err)
+		suite.Equal([]string{"echo", "it's", "$HOME"}, argv)
+
+		_, err = ParseCommand(`["echo", 1]`, CommandOptions{})
+		suite.ErrorIs(err, ErrInvalidCommand)
+
+		_, err = ParseCommand(`["echo"`, CommandOptions{})
+		suite.ErrorIs(err, ErrInvalidCommand)
+	})
+
+	suite.Run("Empty", func() {
+		for _, command := range []string{"", "  ", "''", "[]", `[""]`} {
+			_, err := ParseCommand(command, CommandOptions{})
+			suite.ErrorIs(err, ErrEmptyCommand, command)
+		}
+
+		for _, command := range []string{"", "  ", "[]", `[""]`} {
+			_, err := ParseCommand(command, CommandOptions{Shell: true})
+			suite.ErrorIs(err, ErrEmptyCommand, command)
+		}
+	})
+}
+
+func TestCommand(t *testing.T) {
+	suite.Run(t, new(CommandSuite))
+}
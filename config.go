@@ -0,0 +1,353 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/xmidt-org/chronon"
+)
+
+var (
+	// ErrSwitchName is returned when a configured switch has a blank name.
+	ErrSwitchName = errors.New("A non-empty switch name is required")
+)
+
+// Duration is a time.Duration that marshals to and from JSON using the same
+// string format as the command line, e.g. "1m30s".  A JSON number is
+// interpreted as seconds.  A negative duration is rejected.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	var parsed time.Duration
+	switch value := v.(type) {
+	case nil:
+		return nil
+
+	case float64:
+		parsed = time.Duration(value * float64(time.Second))
+
+	case string:
+		var err error
+		if parsed, err = time.ParseDuration(value); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("Invalid duration: %s", b)
+	}
+
+	if parsed < 0 {
+		return fmt.Errorf("A duration must not be negative: %s", b)
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// PositiveDuration is a Duration which must be positive when it is given in
+// JSON.  TTLs use this type, since a TTL of zero would trigger a switch almost
+// as soon as it is activated.  To use the default, omit the field.
+type PositiveDuration Duration
+
+func (pd PositiveDuration) MarshalJSON() ([]byte, error) {
+	return Duration(pd).MarshalJSON()
+}
+
+func (pd *PositiveDuration) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+
+	var d Duration
+	if err := d.UnmarshalJSON(b); err != nil {
+		return err
+	}
+
+	if d <= 0 {
+		return fmt.Errorf("A duration must be positive: %s", b)
+	}
+
+	*pd = PositiveDuration(d)
+	return nil
+}
+
+// SwitchSpec describes a single named switch within a Config.  The fields
+// mirror the command line options that configure the default switch.
+type SwitchSpec struct {
+	// Name is the required, unique name of the switch.  This name appears in
+	// the switch's URIs, e.g. /switches/{name}/postpone.
+	Name string `json:"name"`
+
+	// Exec holds the commands to execute when this switch triggers.
+	Exec []string `json:"exec"`
+
+	// Dir is the working directory for this switch's commands.
+	Dir string `json:"dir,omitempty"`
+
+	// Shell indicates that this switch's commands are run with /bin/sh -c.
+	Shell bool `json:"shell,omitempty"`
+
+	// ExpandEnv indicates that environment variables in this switch's commands
+	// are expanded when the commands are parsed.
+	ExpandEnv bool `json:"expandEnv,omitempty"`
+
+	// TTL is the interval on which postpones are expected.
+	TTL PositiveDuration `json:"ttl,omitempty"`
+
+	// MinTTL is the minimum TTL that a single postpone may request.
+	MinTTL PositiveDuration `json:"minTTL,omitempty"`
+
+	// MaxTTL is the maximum TTL that a single postpone may request.
+	MaxTTL PositiveDuration `json:"maxTTL,omitempty"`
+
+	// MaxPause is the maximum duration of a pause.
+	MaxPause Duration `json:"maxPause,omitempty"`
+
+	// Misses is the maximum number of missed updates allowed.
+	Misses int `json:"misses,omitempty"`
+
+	// Sources are the optional expected sources of postpones.
+	Sources []SourceSpec `json:"sources,omitempty"`
+
+	// Tiers are the optional escalation tiers.
+	Tiers []TierSpec `json:"tiers,omitempty"`
+
+	// Recover holds the commands to execute when postpones resume after misses.
+	Recover []string `json:"recover,omitempty"`
+
+	// Quorum is the optional weighted number of live sources required.
+	Quorum int `json:"quorum,omitempty"`
+
+	// MinPostpones is the optional number of intervals within BudgetWindow that
+	// must have a postpone.
+	MinPostpones int `json:"minPostpones,omitempty"`
+
+	// BudgetWindow is the number of TTL intervals over which MinPostpones applies.
+	BudgetWindow int `json:"budgetWindow,omitempty"`
+
+	// Grace is the initial period during which misses are not counted.
+	Grace Duration `json:"grace,omitempty"`
+
+	// ArmAfter is the number of consecutive postpones required to arm this switch.
+	ArmAfter int `json:"armAfter,omitempty"`
+
+	// Windows are the optional recurring maintenance windows.
+	Windows []WindowSpec `json:"windows,omitempty"`
+
+	// Rearm indicates whether this switch re-arms after triggering.
+	Rearm bool `json:"rearm,omitempty"`
+
+	// Cooldown is the minimum time after tripping before a postpone re-arms this switch.
+	Cooldown Duration `json:"cooldown,omitempty"`
+
+	// MaxTriggers limits how often actions run within TriggerWindow.
+	MaxTriggers int `json:"maxTriggers,omitempty"`
+
+	// TriggerWindow is the window for MaxTriggers.
+	TriggerWindow Duration `json:"triggerWindow,omitempty"`
+
+	// StateFile is the optional file in which this switch persists its state.
+	StateFile string `json:"stateFile,omitempty"`
+
+	// MarkerFile is the optional trigger marker for this switch.
+	MarkerFile string `json:"markerFile,omitempty"`
+
+	// ActionTimeout is the optional time limit for each of this switch's commands.
+	ActionTimeout Duration `json:"actionTimeout,omitempty"`
+
+	// TriggerTimeout is the optional time limit for all of the commands run by a trigger.
+	TriggerTimeout Duration `json:"triggerTimeout,omitempty"`
+
+	// KillGrace is the time between SIGTERM and SIGKILL for a command that timed out.
+	KillGrace Duration `json:"killGrace,omitempty"`
+
+	// Retries is the number of times to retry each of this switch's commands that fails.
+	Retries int `json:"retries,omitempty"`
+
+	// RetryBackoff is the delay before the first retry, which doubles for each further retry.
+	RetryBackoff Duration `json:"retryBackoff,omitempty"`
+
+	// RetryMaxBackoff is the maximum delay between retries.
+	RetryMaxBackoff Duration `json:"retryMaxBackoff,omitempty"`
+
+	// RetryJitter is the fraction by which each retry delay is randomly varied.
+	RetryJitter float64 `json:"retryJitter,omitempty"`
+
+	// OnFailure is continue, stop-remaining, or abort-and-keep-running, and determines
+	// what a trigger does once a command has failed and exhausted its retries.
+	OnFailure string `json:"onFailure,omitempty"`
+
+	// Parallel is the maximum number of commands that a trigger runs at once.
+	Parallel int `json:"parallel,omitempty"`
+
+	// After holds the dependencies between this switch's Exec commands, e.g. "3=1,2".
+	After []string `json:"after,omitempty"`
+
+	// ExecPolicies holds the retry and failure policies of individual Exec commands,
+	// which override this switch's, e.g. "2=retries:3,on-failure:stop-remaining".
+	ExecPolicies []string `json:"execPolicies,omitempty"`
+
+	// Rules are the optional rules that each heartbeat must pass, e.g. "queue_depth < 1000".
+	Rules []string `json:"rules,omitempty"`
+
+	// Unhealthy is either "miss" or "trigger", and determines what a heartbeat
+	// that fails the Rules does.
+	Unhealthy string `json:"unhealthy,omitempty"`
+}
+
+// SourceSpec describes an expected source of postpones for a named switch.
+type SourceSpec struct {
+	Name   string           `json:"name"`
+	TTL    PositiveDuration `json:"ttl,omitempty"`
+	Misses int              `json:"misses,omitempty"`
+	Weight int              `json:"weight,omitempty"`
+}
+
+// TierSpec describes an escalation tier for a named switch.
+type TierSpec struct {
+	Misses int      `json:"misses"`
+	Exec   []string `json:"exec"`
+}
+
+// WindowSpec describes a recurring maintenance window for a named switch.
+type WindowSpec struct {
+	Start    string   `json:"start"`
+	Duration Duration `json:"duration"`
+	Zone     string   `json:"zone,omitempty"`
+}
+
+// Config is the optional file-based configuration for dms, supplied with --config.
+type Config struct {
+	// Switches are the named switches hosted in addition to the default switch.
+	Switches []SwitchSpec `json:"switches"`
+}
+
+// LoadConfig reads a JSON Config from the given file.
+func LoadConfig(path string) (cfg Config, err error) {
+	var data []byte
+	data, err = os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &cfg)
+	}
+
+	for i := 0; err == nil && i < len(cfg.Switches); i++ {
+		if len(cfg.Switches[i].Name) == 0 {
+			err = ErrSwitchName
+		}
+	}
+
+	return
+}
+
+// switchConfig produces the SwitchConfig for this spec.  Unlike the default switch,
+// a named switch never shuts down the process when it triggers.
+func (spec SwitchSpec) switchConfig(l Logger, c chronon.Clock) (cfg SwitchConfig, err error) {
+	cfg = SwitchConfig{
+		Logger:         PrefixLogger{Prefix: fmt.Sprintf("[switch=%s] ", spec.Name), Logger: l},
+		TTL:            time.Duration(spec.TTL),
+		MinTTL:         time.Duration(spec.MinTTL),
+		MaxTTL:         time.Duration(spec.MaxTTL),
+		MaxPause:       time.Duration(spec.MaxPause),
+		MaxMisses:      spec.Misses,
+		Quorum:         spec.Quorum,
+		MinPostpones:   spec.MinPostpones,
+		BudgetWindow:   spec.BudgetWindow,
+		Grace:          time.Duration(spec.Grace),
+		ArmAfter:       spec.ArmAfter,
+		Rearm:          spec.Rearm,
+		Cooldown:       time.Duration(spec.Cooldown),
+		MaxTriggers:    spec.MaxTriggers,
+		TriggerWindow:  time.Duration(spec.TriggerWindow),
+		StateFile:      spec.StateFile,
+		MarkerFile:     spec.MarkerFile,
+		Unhealthy:      spec.Unhealthy,
+		TriggerTimeout: time.Duration(spec.TriggerTimeout),
+		Parallel:       spec.Parallel,
+		Name:           spec.Name,
+		Clock:          c,
+	}
+
+	switch spec.Unhealthy {
+	case "", UnhealthyMiss, UnhealthyTrigger:
+	default:
+		err = ErrInvalidUnhealthy
+		return
+	}
+
+	if !validOnFailure(spec.OnFailure) {
+		err = ErrInvalidOnFailure
+		return
+	}
+
+	if cfg.After, err = ParseAfter(CommandLine{Exec: spec.Exec, After: spec.After}); err != nil {
+		return
+	}
+
+	if cfg.Rules, err = ParseRules(CommandLine{Rule: spec.Rules}); err != nil {
+		return
+	}
+
+	for _, ss := range spec.Sources {
+		cfg.Sources = append(cfg.Sources, SourceConfig{
+			Name:      ss.Name,
+			TTL:       time.Duration(ss.TTL),
+			MaxMisses: ss.Misses,
+			Weight:    ss.Weight,
+		})
+	}
+
+	for _, ws := range spec.Windows {
+		var w Window
+		if w, err = NewWindow(ws.Start, time.Duration(ws.Duration), ws.Zone); err != nil {
+			return
+		}
+
+		cfg.Windows = append(cfg.Windows, w)
+	}
+
+	commands := CommandLine{
+		Dir:           spec.Dir,
+		Shell:         spec.Shell,
+		ExpandEnv:     spec.ExpandEnv,
+		ActionTimeout: time.Duration(spec.ActionTimeout),
+		KillGrace:     time.Duration(spec.KillGrace),
+
+		Retries:         spec.Retries,
+		RetryBackoff:    time.Duration(spec.RetryBackoff),
+		RetryMaxBackoff: time.Duration(spec.RetryMaxBackoff),
+		RetryJitter:     spec.RetryJitter,
+		OnFailure:       spec.OnFailure,
+	}
+
+	for _, ts := range spec.Tiers {
+		tier := Tier{Misses: ts.Misses}
+		if tier.Actions, err = ParseExec(commands.withExec(ts.Exec)); err != nil {
+			return
+		}
+
+		cfg.Tiers = append(cfg.Tiers, tier)
+	}
+
+	if cfg.RecoverActions, err = ParseExec(commands.withExec(spec.Recover)); err != nil {
+		return
+	}
+
+	commands.Exec, commands.ExecPolicy = spec.Exec, spec.ExecPolicies
+	cfg.Actions, err = ParseExec(commands)
+	return
+}
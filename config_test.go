@@ -0,0 +1,301 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfigSuite struct {
+	DMSSuite
+}
+
+// writeConfig writes the given contents to a temporary config file and returns its path.
+func (suite *ConfigSuite) writeConfig(contents string) string {
+	path := filepath.Join(suite.T().TempDir(), "dms.json")
+	suite.Require().NoError(os.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+func (suite *ConfigSuite) TestDuration() {
+	suite.Run("String", func() {
+		var d Duration
+		suite.Require().NoError(json.Unmarshal([]byte(`"1m30s"`), &d))
+		suite.Equal(90*time.Second, time.Duration(d))
+	})
+
+	suite.Run("Number", func() {
+		var d Duration
+		suite.Require().NoError(json.Unmarshal([]byte(`60`), &d))
+		suite.Equal(time.Minute, time.Duration(d))
+
+		suite.Require().NoError(json.Unmarshal([]byte(`1.5`), &d))
+		suite.Equal(1500*time.Millisecond, time.Duration(d))
+	})
+
+	suite.Run("Invalid", func() {
+		var d Duration
+		suite.Error(json.Unmarshal([]byte(`"nosuch"`), &d))
+		suite.Error(json.Unmarshal([]byte(`true`), &d))
+		suite.Error(json.Unmarshal([]byte(`"-1m"`), &d))
+		suite.Error(json.Unmarshal([]byte(`-60`), &d))
+	})
+
+	suite.Run("Positive", func() {
+		var pd PositiveDuration
+		suite.Require().NoError(json.Unmarshal([]byte(`"1m"`), &pd))
+		suite.Equal(time.Minute, time.Duration(pd))
+		suite.Error(json.Unmarshal([]byte(`"0s"`), &pd))
+		suite.Error(json.Unmarshal([]byte(`0`), &pd))
+		suite.Error(json.Unmarshal([]byte(`"-1m"`), &pd))
+	})
+
+	suite.Run("Marshal", func() {
+		b, err := json.Marshal(Duration(2 * time.Minute))
+		suite.Require().NoError(err)
+		suite.JSONEq(`"2m0s"`, string(b))
+	})
+}
+
+func (suite *ConfigSuite) TestLoadConfig() {
+	suite.Run("Valid", func() {
+		cfg, err := LoadConfig(suite.writeConfig(`{
+			"switches": [
+				{"name": "backup", "exec": ["echo backup"], "ttl": "10m", "misses": 2},
+				{"name": "compaction", "exec": ["echo compaction"], "dir": "/tmp"}
+			]
+		}`))
+
+		suite.Require().NoError(err)
+		suite.Equal(
+			Config{
+				Switches: []SwitchSpec{
+					{Name: "backup", Exec: []string{"echo backup"}, TTL: PositiveDuration(10 * time.Minute), Misses: 2},
+					{Name: "compaction", Exec: []string{"echo compaction"}, Dir: "/tmp"},
+				},
+			},
+			cfg,
+		)
+	})
+
+	suite.Run("MissingName", func() {
+		_, err := LoadConfig(suite.writeConfig(`{"switches": [{"exec": ["ls"]}]}`))
+		suite.ErrorIs(err, ErrSwitchName)
+	})
+
+	suite.Run("Seconds", func() {
+		cfg, err := LoadConfig(suite.writeConfig(`{"switches": [{"name": "backup", "exec": ["ls"], "ttl": 60}]}`))
+		suite.Require().NoError(err)
+		suite.Equal(PositiveDuration(time.Minute), cfg.Switches[0].TTL)
+	})
+
+	suite.Run("NonPositiveTTL", func() {
+		_, err := LoadConfig(suite.writeConfig(`{"switches": [{"name": "backup", "exec": ["ls"], "ttl": "0s"}]}`))
+		suite.Error(err)
+	})
+
+	suite.Run("BadJSON", func() {
+		_, err := LoadConfig(suite.writeConfig(`{`))
+		suite.Error(err)
+	})
+
+	suite.Run("NoSuchFile", func() {
+		_, err := LoadConfig(filepath.Join(suite.T().TempDir(), "nosuch.json"))
+		suite.Error(err)
+	})
+}
+
+func (suite *ConfigSuite) TestSwitchConfig() {
+	suite.Run("Valid", func() {
+		spec := SwitchSpec{Name: "test", Exec: []string{"echo test"}, TTL: PositiveDuration(time.Hour), MinTTL: PositiveDuration(time.Minute), Misses: 3, MinPostpones: 8, BudgetWindow: 12}
+		cfg, err := spec.switchConfig(suite.logger, nil)
+		suite.Require().NoError(err)
+		suite.Equal(time.Hour, cfg.TTL)
+		suite.Equal(time.Minute, cfg.MinTTL)
+		suite.Equal(3, cfg.MaxMisses)
+		suite.Equal(8, cfg.MinPostpones)
+		suite.Equal(12, cfg.BudgetWindow)
+		suite.Len(cfg.Actions, 1) // no Shutdowner for named switches
+		suite.IsType(PrefixLogger{}, cfg.Logger)
+	})
+
+	suite.Run("Tiers", func() {
+		spec := SwitchSpec{
+			Name: "test",
+			Exec: []string{"echo test"},
+			Tiers: []TierSpec{
+				{Misses: 1, Exec: []string{"echo warn"}},
+				{Misses: 2, Exec: []string{"echo page", "echo another"}},
+			},
+		}
+
+		cfg, err := spec.switchConfig(suite.logger, nil)
+		suite.Require().NoError(err)
+		suite.Require().Len(cfg.Tiers, 2)
+		suite.Equal(1, cfg.Tiers[0].Misses)
+		suite.Len(cfg.Tiers[0].Actions, 1)
+		suite.Equal(2, cfg.Tiers[1].Misses)
+		suite.Len(cfg.Tiers[1].Actions, 2)
+	})
+
+	suite.Run("Windows", func() {
+		spec := SwitchSpec{
+			Name:    "test",
+			Exec:    []string{"echo test"},
+			Windows: []WindowSpec{{Start: "0 2 * * SUN", Duration: Duration(2 * time.Hour), Zone: "America/New_York"}},
+		}
+
+		cfg, err := spec.switchConfig(suite.logger, nil)
+		suite.Require().NoError(err)
+		suite.Require().Len(cfg.Windows, 1)
+		suite.Equal(2*time.Hour, cfg.Windows[0].Duration)
+		suite.Equal("America/New_York", cfg.Windows[0].Location.String())
+
+		spec.Windows[0].Zone = "Nosuch/Zone"
+		_, err = spec.switchConfig(suite.logger, nil)
+		suite.ErrorIs(err, ErrInvalidWindow)
+	})
+
+	suite.Run("Rules", func() {
+		spec := SwitchSpec{
+			Name:      "test",
+			Exec:      []string{"echo test"},
+			Rules:     []string{`status == "ok"`, "queue_depth < 1000"},
+			Unhealthy: UnhealthyTrigger,
+		}
+
+		cfg, err := spec.switchConfig(suite.logger, nil)
+		suite.Require().NoError(err)
+		suite.Equal(`status == "ok", queue_depth < 1000`, cfg.Rules.String())
+		suite.Equal(UnhealthyTrigger, cfg.Unhealthy)
+
+		spec.Rules = []string{"queue_depth"}
+		_, err = spec.switchConfig(suite.logger, nil)
+		suite.ErrorIs(err, ErrInvalidRule)
+
+		spec.Rules, spec.Unhealthy = nil, "nosuch"
+		_, err = spec.switchConfig(suite.logger, nil)
+		suite.ErrorIs(err, ErrInvalidUnhealthy)
+	})
+
+	suite.Run("Shell", func() {
+		spec := SwitchSpec{
+			Name:    "test",
+			Exec:    []string{`echo "$DMS_MISSES"`},
+			Recover: []string{`["echo", "recovered"]`},
+			Tiers:   []TierSpec{{Misses: 1, Exec: []string{"echo warn"}}},
+			Dir:     "/",
+			Shell:   true,
+		}
+
+		cfg, err := spec.switchConfig(suite.logger, nil)
+		suite.Require().NoError(err)
+		suite.Require().Len(cfg.Actions, 1)
+		suite.Equal([]string{Shell, "-c", `echo "$DMS_MISSES"`}, cfg.Actions[0].(*ExecAction).Args)
+		suite.Equal("/", cfg.Actions[0].(*ExecAction).Dir)
+		suite.Equal([]string{"echo", "recovered"}, cfg.RecoverActions[0].(*ExecAction).Args)
+		suite.Equal([]string{Shell, "-c", "echo warn"}, cfg.Tiers[0].Actions[0].(*ExecAction).Args)
+
+		spec.ActionTimeout, spec.KillGrace, spec.TriggerTimeout = Duration(time.Minute), Duration(time.Second), Duration(time.Hour)
+		cfg, err = spec.switchConfig(suite.logger, nil)
+		suite.Require().NoError(err)
+		suite.Equal(time.Minute, cfg.Actions[0].(*ExecAction).Timeout)
+		suite.Equal(time.Second, cfg.Tiers[0].Actions[0].(*ExecAction).KillGrace)
+		suite.Equal(time.Hour, cfg.TriggerTimeout)
+
+		spec.Exec = []string{`echo "unterminated`}
+		spec.Shell = false
+		_, err = spec.switchConfig(suite.logger, nil)
+		suite.ErrorIs(err, ErrInvalidCommand)
+	})
+
+	suite.Run("Retry", func() {
+		spec := SwitchSpec{
+			Name:            "test",
+			Exec:            []string{"echo test"},
+			Recover:         []string{"echo recovered"},
+			Retries:         3,
+			RetryBackoff:    Duration(time.Second),
+			RetryMaxBackoff: Duration(time.Minute),
+			RetryJitter:     0.5,
+			OnFailure:       FailureStopRemaining,
+		}
+
+		cfg, err := spec.switchConfig(suite.logger, nil)
+		suite.Require().NoError(err)
+
+		expected := RetryPolicy{
+			Retries:    3,
+			Backoff:    time.Second,
+			MaxBackoff: time.Minute,
+			Jitter:     0.5,
+			OnFailure:  FailureStopRemaining,
+		}
+
+		suite.Equal(expected, cfg.Actions[0].(*ExecAction).Retry)
+		suite.Equal(expected, cfg.RecoverActions[0].(*ExecAction).Retry)
+
+		spec.OnFailure = "nosuch"
+		_, err = spec.switchConfig(suite.logger, nil)
+		suite.ErrorIs(err, ErrInvalidOnFailure)
+	})
+
+	suite.Run("ExecPolicies", func() {
+		spec := SwitchSpec{
+			Name:         "test",
+			Exec:         []string{"echo one", "echo two"},
+			Recover:      []string{"echo recovered"},
+			Retries:      1,
+			ExecPolicies: []string{"2=retries:3,on-failure:abort-and-keep-running"},
+		}
+
+		cfg, err := spec.switchConfig(suite.logger, nil)
+		suite.Require().NoError(err)
+		suite.Equal(RetryPolicy{Retries: 1}, cfg.Actions[0].(*ExecAction).Retry)
+		suite.Equal(RetryPolicy{Retries: 3, OnFailure: FailureAbortAndKeepRunning}, cfg.Actions[1].(*ExecAction).Retry)
+		suite.Equal(RetryPolicy{Retries: 1}, cfg.RecoverActions[0].(*ExecAction).Retry)
+
+		spec.ExecPolicies = []string{"3=retries:3"}
+		_, err = spec.switchConfig(suite.logger, nil)
+		suite.ErrorIs(err, ErrInvalidExecPolicy)
+	})
+
+	suite.Run("Parallel", func() {
+		spec := SwitchSpec{
+			Name:     "test",
+			Exec:     []string{"echo one", "echo two", "echo cleanup"},
+			Parallel: 2,
+			After:    []string{"3=2"},
+		}
+
+		cfg, err := spec.switchConfig(suite.logger, nil)
+		suite.Require().NoError(err)
+		suite.Equal(2, cfg.Parallel)
+		suite.Equal(map[int][]int{2: {1}}, cfg.After)
+
+		spec.After = []string{"4=1"}
+		_, err = spec.switchConfig(suite.logger, nil)
+		suite.ErrorIs(err, ErrInvalidAfter)
+	})
+
+	suite.Run("EmptyCommand", func() {
+		spec := SwitchSpec{Name: "test", Exec: []string{""}}
+		_, err := spec.switchConfig(suite.logger, nil)
+		suite.ErrorIs(err, ErrEmptyCommand)
+
+		spec = SwitchSpec{Name: "test", Exec: []string{"echo test"}, Tiers: []TierSpec{{Exec: []string{""}}}}
+		_, err = spec.switchConfig(suite.logger, nil)
+		suite.ErrorIs(err, ErrEmptyCommand)
+	})
+}
+
+func TestConfig(t *testing.T) {
+	suite.Run(t, new(ConfigSuite))
+}
@@ -0,0 +1,239 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCron is returned by ParseCron to indicate a malformed cron expression.
+	ErrInvalidCron = errors.New("A cron expression must have 5 fields: minute hour day-of-month month day-of-week")
+)
+
+// cronField describes the allowed values of a single field of a cron expression.
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute     = cronField{name: "minute", min: 0, max: 59}
+	cronHour       = cronField{name: "hour", min: 0, max: 23}
+	cronDayOfMonth = cronField{name: "day-of-month", min: 1, max: 31}
+
+	cronMonth = cronField{
+		name: "month", min: 1, max: 12,
+		names: map[string]int{
+			"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+			"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+		},
+	}
+
+	// day-of-week allows 7 as an alias for Sunday
+	cronDayOfWeek = cronField{
+		name: "day-of-week", min: 0, max: 7,
+		names: map[string]int{
+			"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+		},
+	}
+
+	// cronDescriptors are the supported shorthand expressions.
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// value parses a single number or name for this field.
+func (f cronField) value(v string) (int, error) {
+	if n, ok := f.names[strings.ToUpper(v)]; ok {
+		return n, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err == nil && (n < f.min || n > f.max) {
+		err = fmt.Errorf("%s value %d is not in [%d, %d]", f.name, n, f.min, f.max)
+	}
+
+	return n, err
+}
+
+// parse parses a comma-separated list of values, ranges, and steps into a bit set.
+func (f cronField) parse(expr string) (bits uint64, err error) {
+	for _, item := range strings.Split(expr, ",") {
+		var (
+			rng, stepValue, hasStep = strings.Cut(item, "/")
+			lo, hi                  = f.min, f.max
+			step                    = 1
+		)
+
+		if hasStep {
+			if step, err = strconv.Atoi(stepValue); err == nil && step < 1 {
+				err = fmt.Errorf("%s step %d is not positive", f.name, step)
+			}
+		}
+
+		if err == nil && rng != "*" {
+			first, last, isRange := strings.Cut(rng, "-")
+			if lo, err = f.value(first); err == nil {
+				switch {
+				case isRange:
+					hi, err = f.value(last)
+
+				case !hasStep:
+					// a single value
+					hi = lo
+				}
+			}
+		}
+
+		if err == nil && lo > hi {
+			err = fmt.Errorf("%s range %s is empty", f.name, rng)
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		for i := lo; i <= hi; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return
+}
+
+// Cron is a parsed, standard 5-field cron expression.  Each field may be a
+// '*', a value, a range, or a comma-separated list of these, and each may be
+// followed by a /step.  Months and days of the week may be given by their
+// three-letter English names.  The descriptors @yearly, @annually, @monthly,
+// @weekly, @daily, @midnight, and @hourly are also supported.
+//
+// As with cron, when both the day-of-month and day-of-week are restricted,
+// a day matches if either field matches.
+type Cron struct {
+	expr string
+
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+
+	// anyDayOfMonth and anyDayOfWeek are true when the corresponding field is a '*'
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+// ParseCron parses a cron expression.
+func ParseCron(expr string) (c Cron, err error) {
+	c.expr = expr
+	if d, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		expr = d
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Cron{}, ErrInvalidCron
+	}
+
+	if c.minute, err = cronMinute.parse(fields[0]); err == nil {
+		c.hour, err = cronHour.parse(fields[1])
+	}
+
+	if err == nil {
+		c.dayOfMonth, err = cronDayOfMonth.parse(fields[2])
+	}
+
+	if err == nil {
+		c.month, err = cronMonth.parse(fields[3])
+	}
+
+	if err == nil {
+		c.dayOfWeek, err = cronDayOfWeek.parse(fields[4])
+	}
+
+	if err != nil {
+		return Cron{}, errors.Join(ErrInvalidCron, err)
+	}
+
+	// fold Sunday=7 into Sunday=0
+	if c.dayOfWeek&(1<<7) != 0 {
+		c.dayOfWeek |= 1
+	}
+
+	c.anyDayOfMonth = strings.HasPrefix(fields[2], "*")
+	c.anyDayOfWeek = strings.HasPrefix(fields[4], "*")
+	return
+}
+
+// String returns the original expression.
+func (c Cron) String() string {
+	return c.expr
+}
+
+// day tests if the given time falls on a day matched by this expression.
+func (c Cron) day(t time.Time) bool {
+	var (
+		dom = c.dayOfMonth&(1<<uint(t.Day())) != 0
+		dow = c.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	)
+
+	if c.anyDayOfMonth || c.anyDayOfWeek {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// forward returns next if it is after t, or the minute after t otherwise.
+// This guards against time.Date normalizing a wall time which does not exist
+// in a location, such as a midnight skipped by a DST change, to an earlier time.
+func forward(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+
+	return t.Add(time.Minute)
+}
+
+// Next returns the first minute strictly after t that matches this expression,
+// evaluated in t's location.  If nothing matches within the next 5 years, e.g.
+// for February 30th, the zero time is returned.
+func (c Cron) Next(t time.Time) time.Time {
+	var (
+		loc   = t.Location()
+		limit = t.Year() + 5
+	)
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for t.Year() <= limit {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = forward(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+
+		case !c.day(t):
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			// add elapsed time rather than using time.Date, which can move
+			// backward when the next hour is skipped by a DST change
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CronSuite struct {
+	DMSSuite
+}
+
+func (suite *CronSuite) TestParseCron() {
+	suite.Run("Invalid", func() {
+		for _, expr := range []string{
+			"",
+			"* * * *",
+			"* * * * * *",
+			"60 * * * *",
+			"* 24 * * *",
+			"* * 0 * *",
+			"* * * 13 *",
+			"* * * * 8",
+			"*/0 * * * *",
+			"5-1 * * * *",
+			"x * * * *",
+			"* * * NOV-FOO *",
+			"@nosuch",
+		} {
+			_, err := ParseCron(expr)
+			suite.ErrorIs(err, ErrInvalidCron, expr)
+		}
+	})
+
+	suite.Run("Valid", func() {
+		c, err := ParseCron("0,30 */6 1-7 JAN-jun,dec mon-fri")
+		suite.Require().NoError(err)
+		suite.Equal("0,30 */6 1-7 JAN-jun,dec mon-fri", c.String())
+		suite.Equal(uint64(1|1<<30), c.minute)
+		suite.Equal(uint64(1|1<<6|1<<12|1<<18), c.hour)
+		suite.Equal(uint64(0xfe), c.dayOfMonth)
+		suite.Equal(uint64(0x7e|1<<12), c.month)
+		suite.Equal(uint64(0x3e), c.dayOfWeek)
+	})
+
+	suite.Run("Sunday", func() {
+		c, err := ParseCron("0 0 * * 7")
+		suite.Require().NoError(err)
+		suite.Equal(uint64(1|1<<7), c.dayOfWeek)
+	})
+}
+
+func (suite *CronSuite) TestNext() {
+	var (
+		ny, _ = time.LoadLocation("America/New_York")
+
+		// a Saturday
+		start = time.Date(2026, time.January, 3, 12, 34, 56, 0, time.UTC)
+	)
+
+	testData := []struct {
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{"* * * * *", start, time.Date(2026, time.January, 3, 12, 35, 0, 0, time.UTC)},
+		{"*/15 * * * *", start, time.Date(2026, time.January, 3, 12, 45, 0, 0, time.UTC)},
+		{"@hourly", start, time.Date(2026, time.January, 3, 13, 0, 0, 0, time.UTC)},
+		{"@daily", start, time.Date(2026, time.January, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 2 * * SUN", start, time.Date(2026, time.January, 4, 2, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", start, time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", start, time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+
+		// day-of-month or day-of-week when both are restricted
+		{"0 0 13 * FRI", start, time.Date(2026, time.January, 9, 0, 0, 0, 0, time.UTC)},
+
+		// the result is strictly after the starting time
+		{"34 12 * * *", start.Truncate(time.Minute), time.Date(2026, time.January, 4, 12, 34, 0, 0, time.UTC)},
+
+		// evaluated in the starting time's location, across a DST change
+		{"0 2 * * SUN", time.Date(2026, time.March, 1, 3, 0, 0, 0, ny), time.Date(2026, time.March, 15, 2, 0, 0, 0, ny)},
+		{"30 1 * * *", time.Date(2026, time.November, 1, 0, 0, 0, 0, ny), time.Date(2026, time.November, 1, 1, 30, 0, 0, ny)},
+
+		// nothing matches
+		{"0 0 30 FEB *", start, time.Time{}},
+	}
+
+	for _, testCase := range testData {
+		suite.Run(testCase.expr, func() {
+			c, err := ParseCron(testCase.expr)
+			suite.Require().NoError(err)
+			actual := c.Next(testCase.from)
+			suite.True(testCase.expected.Equal(actual), "expected %s, got %s", testCase.expected, actual)
+		})
+	}
+}
+
+func TestCron(t *testing.T) {
+	suite.Run(t, new(CronSuite))
+}
@@ -0,0 +1,226 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/fx"
+)
+
+const (
+	// EventPostpone is the type of event published when a switch is postponed.
+	// Its Data is a PostponeEvent.
+	EventPostpone = "postpone"
+
+	// EventMiss is the type of event published when a switch counts a miss.
+	// Its Data is a MissEvent.
+	EventMiss = "miss"
+
+	// EventTrigger is the type of event published when a switch triggers its
+	// actions.  Its Data is a TriggerEvent.
+	EventTrigger = "trigger"
+
+	// EventDeactivate is the type of event published when a switch is deactivated.
+	// It has no Data.
+	EventDeactivate = "deactivate"
+
+	// EventAction is the type of event published after a switch runs one of its
+	// actions.  Its Data is an ActionEvent.
+	EventAction = "action"
+)
+
+// DefaultEventBuffer is the number of events buffered for each subscriber.
+const DefaultEventBuffer = 64
+
+// Event is something that happened to a switch.
+type Event struct {
+	// Type is one of the Event constants, and determines the type of Data.
+	Type string `json:"type"`
+
+	// Time is the time at which the event occurred, according to the switch's clock.
+	Time time.Time `json:"time"`
+
+	// Switch is the name of the switch.  This is unset for the default switch.
+	Switch string `json:"switch,omitempty"`
+
+	// Data is the payload specific to the Type.
+	Data any `json:"data,omitempty"`
+}
+
+// PostponeEvent is the Data for an EventPostpone.
+type PostponeEvent struct {
+	Source     string   `json:"source,omitempty"`
+	RemoteAddr string   `json:"remoteAddr,omitempty"`
+	TTL        Duration `json:"ttl,omitempty"`
+}
+
+// MissEvent is the Data for an EventMiss.
+type MissEvent struct {
+	Misses    int `json:"misses"`
+	MaxMisses int `json:"maxMisses"`
+}
+
+// TriggerEvent is the Data for an EventTrigger.
+type TriggerEvent struct {
+	Misses  int     `json:"misses"`
+	Details Details `json:"details,omitempty"`
+}
+
+// ActionEvent is the Data for an EventAction.
+type ActionEvent struct {
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Events fans out switch events to any number of subscribers.  Publishing never
+// blocks.  A subscriber that falls so far behind that its buffer fills up is
+// dropped, and its channel is closed.
+//
+// Events is a SwitchListener, which publishes an Event for each callback.
+type Events struct {
+	lock        sync.Mutex
+	buffer      int
+	closed      bool
+	subscribers map[chan Event]bool
+}
+
+// NewEvents creates an Events that buffers the given number of events per
+// subscriber.  If buffer is nonpositive, DefaultEventBuffer is used.
+func NewEvents(buffer int) *Events {
+	if buffer <= 0 {
+		buffer = DefaultEventBuffer
+	}
+
+	return &Events{
+		buffer:      buffer,
+		subscribers: make(map[chan Event]bool),
+	}
+}
+
+// Publish sends an event to every subscriber.
+func (e *Events) Publish(ev Event) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for ch := range e.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			// this subscriber isn't keeping up
+			delete(e.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns a channel of published events along with a function that
+// cancels the subscription.  The channel is closed when the subscription is
+// cancelled, when the subscriber is dropped, or when this Events is closed.
+func (e *Events) Subscribe() (<-chan Event, func()) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	ch := make(chan Event, e.buffer)
+	if e.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	e.subscribers[ch] = true
+	return ch, func() {
+		e.lock.Lock()
+		defer e.lock.Unlock()
+
+		if e.subscribers[ch] {
+			delete(e.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close closes every subscriber's channel.  Subsequent subscriptions are
+// closed immediately.
+func (e *Events) Close() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.closed = true
+	for ch := range e.subscribers {
+		delete(e.subscribers, ch)
+		close(ch)
+	}
+}
+
+var _ SwitchListener = (*Events)(nil)
+
+func (e *Events) OnPostpone(name string, t time.Time, pr PostponeRequest) {
+	e.Publish(Event{
+		Type:   EventPostpone,
+		Time:   t,
+		Switch: name,
+		Data: PostponeEvent{
+			Source:     pr.Source,
+			RemoteAddr: pr.RemoteAddr,
+			TTL:        Duration(pr.TTL),
+		},
+	})
+}
+
+func (e *Events) OnMiss(name string, t time.Time, misses, maxMisses int) {
+	e.Publish(Event{
+		Type:   EventMiss,
+		Time:   t,
+		Switch: name,
+		Data:   MissEvent{Misses: misses, MaxMisses: maxMisses},
+	})
+}
+
+func (e *Events) OnTrigger(name string, t time.Time, misses int, d Details) {
+	e.Publish(Event{
+		Type:   EventTrigger,
+		Time:   t,
+		Switch: name,
+		Data:   TriggerEvent{Misses: misses, Details: d},
+	})
+}
+
+func (e *Events) OnDeactivate(name string, t time.Time) {
+	e.Publish(Event{
+		Type:   EventDeactivate,
+		Time:   t,
+		Switch: name,
+	})
+}
+
+func (e *Events) OnActionResult(name string, t time.Time, a Action, err error) {
+	ae := ActionEvent{Action: a.String()}
+	if err != nil {
+		ae.Error = err.Error()
+	}
+
+	e.Publish(Event{
+		Type:   EventAction,
+		Time:   t,
+		Switch: name,
+		Data:   ae,
+	})
+}
+
+// provideEvents creates an fx.Option that provides the *Events shared by all switches.
+// The *Events is also added to the listeners of every switch.
+func provideEvents() fx.Option {
+	return fx.Provide(
+		func() *Events {
+			return NewEvents(DefaultEventBuffer)
+		},
+		fx.Annotate(
+			func(e *Events) SwitchListener {
+				return e
+			},
+			fx.ResultTags(`group:"listeners"`),
+		),
+	)
+}
@@ -0,0 +1,197 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/xmidt-org/chronon"
+)
+
+type EventsSuite struct {
+	DMSSuite
+}
+
+// drain returns the events currently buffered in a subscription.
+func (suite *EventsSuite) drain(ch <-chan Event) (events []Event) {
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			events = append(events, e)
+
+		default:
+			return
+		}
+	}
+}
+
+func (suite *EventsSuite) TestPublish() {
+	var (
+		e        = NewEvents(0)
+		ch1, c1  = e.Subscribe()
+		ch2, c2  = e.Subscribe()
+		expected = Event{Type: EventPostpone, Time: suite.now, Data: PostponeEvent{Source: "test"}}
+	)
+
+	e.Publish(expected)
+	suite.Equal([]Event{expected}, suite.drain(ch1))
+	suite.Equal([]Event{expected}, suite.drain(ch2))
+
+	// a cancelled subscription is closed, and cancelling is idempotent
+	c1()
+	c1()
+	_, ok := <-ch1
+	suite.False(ok)
+
+	e.Publish(expected)
+	suite.Equal([]Event{expected}, suite.drain(ch2))
+
+	e.Close()
+	_, ok = <-ch2
+	suite.False(ok)
+	c2()
+
+	ch3, c3 := e.Subscribe()
+	_, ok = <-ch3
+	suite.False(ok)
+	c3()
+}
+
+func (suite *EventsSuite) TestSlowSubscriber() {
+	var (
+		e          = NewEvents(2)
+		slow, _    = e.Subscribe()
+		fast, stop = e.Subscribe()
+	)
+
+	defer stop()
+	for i := 0; i < 3; i++ {
+		e.Publish(Event{Type: EventMiss, Data: MissEvent{Misses: i}})
+		suite.Len(suite.drain(fast), 1)
+	}
+
+	// the slow subscriber was dropped rather than blocking the publisher
+	suite.Len(suite.drain(slow), 2)
+	_, ok := <-slow
+	suite.False(ok)
+
+	e.Publish(Event{Type: EventDeactivate})
+	suite.Len(suite.drain(fast), 1)
+}
+
+func (suite *EventsSuite) TestMarshal() {
+	data, err := json.Marshal(Event{
+		Type:   EventTrigger,
+		Time:   time.Date(2026, time.January, 4, 2, 0, 0, 0, time.UTC),
+		Switch: "backup",
+		Data:   TriggerEvent{Misses: 2, Details: Details{MissesDetail: "2"}},
+	})
+
+	suite.Require().NoError(err)
+	suite.JSONEq(
+		`{"type": "trigger", "time": "2026-01-04T02:00:00Z", "switch": "backup", "data": {"misses": 2, "details": {"misses": "2"}}}`,
+		string(data),
+	)
+}
+
+func (suite *EventsSuite) TestSwitch() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 2, mockActions.actions()...)
+			events      = NewEvents(0)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		ch, cancel := events.Subscribe()
+		defer cancel()
+
+		cfg.Name = "test"
+		cfg.Listeners = []SwitchListener{events}
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+		suite.True(s.Postpone(PostponeRequest{Source: "source", RemoteAddr: "127.0.0.1:1234", TTL: ttl}))
+		synctest.Wait()
+		suite.Equal(
+			[]Event{{
+				Type:   EventPostpone,
+				Time:   clock.Now(),
+				Switch: "test",
+				Data:   PostponeEvent{Source: "source", RemoteAddr: "127.0.0.1:1234", TTL: Duration(ttl)},
+			}},
+			suite.drain(ch),
+		)
+
+		clock.Add(ttl)
+		synctest.Wait()
+		suite.Equal(
+			[]Event{{
+				Type:   EventMiss,
+				Time:   clock.Now(),
+				Switch: "test",
+				Data:   MissEvent{Misses: 1, MaxMisses: 2},
+			}},
+			suite.drain(ch),
+		)
+
+		calls := mockActions.expectRunOnce(nil)
+		clock.Add(ttl)
+		mockActions.waitForCalls(suite.T(), time.Second, calls)
+		suite.NoError(<-done)
+		mockActions.assertExpectations(suite.T())
+
+		published := suite.drain(ch)
+		suite.Require().Len(published, 3)
+		suite.Equal(EventMiss, published[0].Type)
+		suite.Equal(EventTrigger, published[1].Type)
+		suite.Equal(2, published[1].Data.(TriggerEvent).Misses)
+		suite.Equal(EventAction, published[2].Type)
+		suite.Equal(ActionEvent{Action: "action[0]"}, published[2].Data)
+	})
+}
+
+func (suite *EventsSuite) TestDeactivate() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			cfg, clock = suite.switchConfig(10*time.Second, 1)
+			events     = NewEvents(0)
+			done       = make(chan error)
+			onTicker   = make(chan chronon.FakeTicker, 1)
+		)
+
+		ch, cancel := events.Subscribe()
+		defer cancel()
+
+		cfg.Listeners = []SwitchListener{events}
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+		suite.Equal([]Event{{Type: EventDeactivate, Time: clock.Now()}}, suite.drain(ch))
+	})
+}
+
+func TestEvents(t *testing.T) {
+	suite.Run(t, new(EventsSuite))
+}
@@ -0,0 +1,239 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// UnhealthyMiss is the default unhealthy mode in which a heartbeat that fails a switch's
+	// rules is not counted as a postpone.  The switch counts a miss as usual once
+	// its deadline passes.
+	UnhealthyMiss = "miss"
+
+	// UnhealthyTrigger is the unhealthy mode in which a heartbeat that fails a
+	// switch's rules triggers the switch immediately.
+	UnhealthyTrigger = "trigger"
+
+	// StatusField is the Rule field that refers to a heartbeat's status.  All other
+	// fields refer to a heartbeat's metrics.
+	StatusField = "status"
+)
+
+var (
+	// ErrInvalidRule is returned by ParseRule when a rule is not of the form
+	// field operator value.
+	ErrInvalidRule = errors.New("A rule must be of the form field operator value, e.g. queue_depth < 1000")
+
+	// ErrInvalidUnhealthy indicates an unhealthy mode other than UnhealthyMiss or UnhealthyTrigger.
+	ErrInvalidUnhealthy = errors.New("The unhealthy mode must be either miss or trigger")
+)
+
+// Heartbeat is the optional payload of a postpone, which describes the health of
+// the postponing service.
+type Heartbeat struct {
+	// Status is the service's self-reported status, e.g. "ok".
+	Status string `json:"status,omitempty"`
+
+	// Metrics are arbitrary named values.  Numbers, strings, and booleans can be
+	// checked by rules.
+	Metrics map[string]any `json:"metrics,omitempty"`
+}
+
+// field returns the named value of this heartbeat.  This method returns false if
+// this heartbeat has no such value.
+func (hb *Heartbeat) field(name string) (v any, ok bool) {
+	switch {
+	case hb == nil:
+		return
+
+	case name == StatusField:
+		v, ok = hb.Status, len(hb.Status) > 0
+
+	default:
+		v, ok = hb.Metrics[name]
+	}
+
+	return
+}
+
+// operators are the Rule operators, with the two character operators first so
+// that they are matched in preference to their one character prefixes.
+var operators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// Rule is a single check of a heartbeat's field.
+type Rule struct {
+	// Field is the StatusField or the name of a metric.
+	Field string
+
+	// Operator is one of ==, !=, <, <=, >, or >=.
+	Operator string
+
+	// Value is the float64, string, or bool against which the field is compared.
+	Value any
+}
+
+// String returns the textual form of this rule, as accepted by ParseRule.
+func (r Rule) String() string {
+	if s, ok := r.Value.(string); ok {
+		return fmt.Sprintf("%s %s %q", r.Field, r.Operator, s)
+	}
+
+	return fmt.Sprintf("%s %s %v", r.Field, r.Operator, r.Value)
+}
+
+// Check returns true if the given heartbeat passes this rule.  A heartbeat fails
+// a rule if it does not have the rule's field, or if the field's type differs from
+// the rule's value.  Booleans can only be compared for equality.
+func (r Rule) Check(hb *Heartbeat) bool {
+	v, ok := hb.field(r.Field)
+	if !ok {
+		return false
+	}
+
+	var c int
+	switch rv := r.Value.(type) {
+	case float64:
+		fv, ok := v.(float64)
+		if !ok {
+			return false
+		}
+
+		switch {
+		case fv < rv:
+			c = -1
+		case fv > rv:
+			c = 1
+		}
+
+	case string:
+		sv, ok := v.(string)
+		if !ok {
+			return false
+		}
+
+		c = strings.Compare(sv, rv)
+
+	case bool:
+		bv, ok := v.(bool)
+		if !ok {
+			return false
+		}
+
+		switch r.Operator {
+		case "==":
+			return bv == rv
+		case "!=":
+			return bv != rv
+		default:
+			return false
+		}
+	}
+
+	switch r.Operator {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// parseValue parses a rule's value.  A quoted value is a string.  Otherwise,
+// true and false are bools, a value that parses as a number is a float64, and
+// anything else is a string.
+func parseValue(v string) any {
+	if s, err := strconv.Unquote(v); err == nil {
+		return s
+	}
+
+	switch v {
+	case "true":
+		return true
+
+	case "false":
+		return false
+	}
+
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+
+	return v
+}
+
+// ParseRule parses a rule of the form field operator value, e.g. status == "ok"
+// or queue_depth < 1000.
+func ParseRule(v string) (r Rule, err error) {
+	i := strings.IndexAny(v, "=!<>")
+	if i < 0 {
+		return Rule{}, ErrInvalidRule
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(v[i:], op) {
+			r.Operator = op
+			break
+		}
+	}
+
+	r.Field = strings.TrimSpace(v[:i])
+	value := strings.TrimSpace(v[i+len(r.Operator):])
+	if len(r.Operator) == 0 || len(r.Field) == 0 || len(value) == 0 {
+		return Rule{}, ErrInvalidRule
+	}
+
+	r.Value = parseValue(value)
+	return
+}
+
+// Rules is a set of rules that a heartbeat must pass in order to postpone a switch.
+type Rules []Rule
+
+// Failed returns the rules that the given heartbeat does not pass.
+func (rs Rules) Failed(hb *Heartbeat) (failed Rules) {
+	for _, r := range rs {
+		if !r.Check(hb) {
+			failed = append(failed, r)
+		}
+	}
+
+	return
+}
+
+// String returns the rules, separated by commas.
+func (rs Rules) String() string {
+	s := make([]string, 0, len(rs))
+	for _, r := range rs {
+		s = append(s, r.String())
+	}
+
+	return strings.Join(s, ", ")
+}
+
+// ParseRules parses each of the --rule values from a command line.
+func ParseRules(cl CommandLine) (Rules, error) {
+	var rs Rules
+	for _, v := range cl.Rule {
+		r, err := ParseRule(v)
+		if err != nil {
+			return nil, err
+		}
+
+		rs = append(rs, r)
+	}
+
+	return rs, nil
+}
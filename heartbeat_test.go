@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type HeartbeatSuite struct {
+	DMSSuite
+}
+
+func (suite *HeartbeatSuite) TestParseRule() {
+	suite.Run("Valid", func() {
+		testData := []struct {
+			rule     string
+			expected Rule
+			text     string
+		}{
+			{`status == "ok"`, Rule{Field: "status", Operator: "==", Value: "ok"}, `status == "ok"`},
+			{"status!=degraded", Rule{Field: "status", Operator: "!=", Value: "degraded"}, `status != "degraded"`},
+			{"queue_depth < 1000", Rule{Field: "queue_depth", Operator: "<", Value: 1000.0}, "queue_depth < 1000"},
+			{"error_rate <= 0.5", Rule{Field: "error_rate", Operator: "<=", Value: 0.5}, "error_rate <= 0.5"},
+			{"workers > 2", Rule{Field: "workers", Operator: ">", Value: 2.0}, "workers > 2"},
+			{"workers >= 2", Rule{Field: "workers", Operator: ">=", Value: 2.0}, "workers >= 2"},
+			{"leader == true", Rule{Field: "leader", Operator: "==", Value: true}, "leader == true"},
+			{`version == "2"`, Rule{Field: "version", Operator: "==", Value: "2"}, `version == "2"`},
+		}
+
+		for _, testCase := range testData {
+			r, err := ParseRule(testCase.rule)
+			suite.Require().NoError(err, testCase.rule)
+			suite.Equal(testCase.expected, r)
+			suite.Equal(testCase.text, r.String())
+		}
+	})
+
+	suite.Run("Invalid", func() {
+		for _, v := range []string{
+			"",
+			"status",
+			"== ok",
+			"status ==",
+			"status = ok",
+			"status ! ok",
+		} {
+			_, err := ParseRule(v)
+			suite.ErrorIs(err, ErrInvalidRule, v)
+		}
+	})
+
+	suite.Run("ParseRules", func() {
+		rules, err := ParseRules(CommandLine{Rule: []string{`status == "ok"`, "queue_depth < 1000"}})
+		suite.NoError(err)
+		suite.Len(rules, 2)
+
+		_, err = ParseRules(CommandLine{Rule: []string{`status == "ok"`, "queue_depth"}})
+		suite.ErrorIs(err, ErrInvalidRule)
+	})
+}
+
+func (suite *HeartbeatSuite) TestCheck() {
+	hb := &Heartbeat{
+		Status: "ok",
+		Metrics: map[string]any{
+			"queue_depth": 12.0,
+			"region":      "east",
+			"leader":      true,
+		},
+	}
+
+	testData := []struct {
+		rule     string
+		expected bool
+	}{
+		{`status == "ok"`, true},
+		{`status != "ok"`, false},
+		{"queue_depth < 1000", true},
+		{"queue_depth <= 12", true},
+		{"queue_depth > 12", false},
+		{"queue_depth >= 12", true},
+		{"queue_depth == 12", true},
+		{"queue_depth != 12", false},
+		{"region == east", true},
+		{`region < "west"`, true},
+		{"leader == true", true},
+		{"leader != true", false},
+		{"leader < true", false},
+		{"missing < 1000", false},
+		{"queue_depth == east", false},
+		{"region < 1000", false},
+		{"leader == 1", false},
+	}
+
+	for _, testCase := range testData {
+		r, err := ParseRule(testCase.rule)
+		suite.Require().NoError(err)
+		suite.Equal(testCase.expected, r.Check(hb), testCase.rule)
+	}
+
+	suite.Run("NoHeartbeat", func() {
+		r, err := ParseRule(`status == "ok"`)
+		suite.Require().NoError(err)
+		suite.False(r.Check(nil))
+		suite.False(r.Check(&Heartbeat{Metrics: map[string]any{"queue_depth": 1.0}}))
+	})
+}
+
+func (suite *HeartbeatSuite) TestFailed() {
+	rules, err := ParseRules(CommandLine{Rule: []string{`status == "ok"`, "queue_depth < 1000"}})
+	suite.Require().NoError(err)
+
+	suite.Empty(rules.Failed(&Heartbeat{Status: "ok", Metrics: map[string]any{"queue_depth": 1.0}}))
+	suite.Equal(
+		"queue_depth < 1000",
+		rules.Failed(&Heartbeat{Status: "ok", Metrics: map[string]any{"queue_depth": 5000.0}}).String(),
+	)
+
+	suite.Equal(rules, rules.Failed(nil))
+	suite.Empty(Rules(nil).Failed(nil))
+}
+
+func (suite *HeartbeatSuite) TestPostponeRequest() {
+	pr := PostponeRequest{Source: "test", Heartbeat: &Heartbeat{Status: "degraded"}}
+	suite.Equal("[source=test] [status=degraded]", pr.String())
+
+	pr.Heartbeat = &Heartbeat{Metrics: map[string]any{"queue_depth": 1.0}}
+	suite.Equal("[source=test]", pr.String())
+}
+
+func TestHeartbeat(t *testing.T) {
+	suite.Run(t, new(HeartbeatSuite))
+}
@@ -5,12 +5,20 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/xmidt-org/chronon"
 	"go.uber.org/fx"
 )
 
@@ -19,8 +27,85 @@ const (
 	// remote entity that is postponing action triggers.
 	SourceParameter = "source"
 
+	// TTLParameter is the name of the HTTP query or form parameter holding the
+	// TTL requested by a single postpone.
+	TTLParameter = "ttl"
+
 	// PostponePath is the URI path for the postpone handler.
 	PostponePath = "/postpone"
+
+	// RearmPath is the URI path for the rearm handler.
+	RearmPath = "/rearm"
+
+	// ReasonParameter is the name of the HTTP query or form parameter holding
+	// the reason for a pause.
+	ReasonParameter = "reason"
+
+	// DurationParameter is the name of the HTTP query or form parameter holding
+	// the maximum duration of a pause.
+	DurationParameter = "duration"
+
+	// ConfirmParameter is the name of the HTTP query or form parameter holding the
+	// confirmation token for a manual trigger.
+	ConfirmParameter = "confirm"
+
+	// TriggerPath is the URI path for the manual trigger handler.
+	TriggerPath = "/trigger"
+
+	// DeactivatePath is the URI path for the deactivate handler.
+	DeactivatePath = "/deactivate"
+
+	// AbortPath is the URI path for the abort handler.
+	AbortPath = "/abort"
+
+	// StatusPath is the URI path for the status handler.
+	StatusPath = "/status"
+
+	// EventsPath is the URI path for the server-sent events handler.
+	EventsPath = "/events"
+
+	// PausePath is the URI path for the pause handler.
+	PausePath = "/pause"
+
+	// ResumePath is the URI path for the resume handler.
+	ResumePath = "/resume"
+
+	// SwitchNameVariable is the name of the URI path variable holding the name
+	// of a switch in a Registry.
+	SwitchNameVariable = "name"
+
+	// SwitchPostponePath is the URI path for the postpone handler of a named switch.
+	SwitchPostponePath = "/switches/{" + SwitchNameVariable + "}/postpone"
+
+	// SwitchRearmPath is the URI path for the rearm handler of a named switch.
+	SwitchRearmPath = "/switches/{" + SwitchNameVariable + "}/rearm"
+
+	// SwitchTriggerPath is the URI path for the manual trigger handler of a named switch.
+	SwitchTriggerPath = "/switches/{" + SwitchNameVariable + "}/trigger"
+
+	// SwitchDeactivatePath is the URI path for the deactivate handler of a named switch.
+	SwitchDeactivatePath = "/switches/{" + SwitchNameVariable + "}/deactivate"
+
+	// SwitchAbortPath is the URI path for the abort handler of a named switch.
+	SwitchAbortPath = "/switches/{" + SwitchNameVariable + "}/abort"
+
+	// SwitchStatusPath is the URI path for the status handler of a named switch.
+	SwitchStatusPath = "/switches/{" + SwitchNameVariable + "}/status"
+
+	// SwitchPausePath is the URI path for the pause handler of a named switch.
+	SwitchPausePath = "/switches/{" + SwitchNameVariable + "}/pause"
+
+	// SwitchResumePath is the URI path for the resume handler of a named switch.
+	SwitchResumePath = "/switches/{" + SwitchNameVariable + "}/resume"
+)
+
+// DefaultConfirmTTL is how long a manual trigger's confirmation token remains valid.
+const DefaultConfirmTTL = time.Minute
+
+var (
+	// ErrInvalidConfirmation is returned when a manual trigger is confirmed with a
+	// token that is unknown, expired, or was issued to a different caller or switch.
+	ErrInvalidConfirmation = errors.New("That confirmation token is invalid or has expired")
 )
 
 type notFoundHandler struct {
@@ -41,11 +126,143 @@ func (mnah methodNotAllowedHandler) ServeHTTP(response http.ResponseWriter, requ
 	response.WriteHeader(http.StatusMethodNotAllowed)
 }
 
+// postponeBody is the optional JSON body of a postpone request.
+type postponeBody struct {
+	Source string           `json:"source"`
+	TTL    PositiveDuration `json:"ttl"`
+
+	Heartbeat
+}
+
+// PostponeHandler postpones a switch.  The optional source and TTL are taken from
+// the SourceParameter and TTLParameter.  Alternatively, a request with a JSON
+// content type may supply them in its body.  Form values take precedence.
+//
+// A requested TTL must be positive.  If the Postponer is a TTLChecker, a TTL below
+// its minimum is also rejected with http.StatusBadRequest.
+//
+// A JSON body may also carry a heartbeat, as a status and a metrics object, which
+// the switch checks against its rules.  If the Postponer is a HeartbeatChecker, a
+// heartbeat that fails any rule results in http.StatusUnprocessableEntity.
 type PostponeHandler struct {
 	Postponer Postponer
 }
 
+// postponeRequest builds a PostponeRequest from an HTTP request.
+func (ph PostponeHandler) postponeRequest(request *http.Request) (pr PostponeRequest, err error) {
+	if err = request.ParseForm(); err != nil {
+		return
+	}
+
+	var body postponeBody
+	if mt, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type")); mt == "application/json" {
+		if err = json.NewDecoder(request.Body).Decode(&body); err == io.EOF {
+			err = nil // an empty body is allowed
+		} else if err != nil {
+			return
+		}
+	}
+
+	pr = PostponeRequest{
+		Source:     request.Form.Get(SourceParameter),
+		RemoteAddr: request.RemoteAddr,
+		TTL:        time.Duration(body.TTL),
+	}
+
+	if len(body.Status) > 0 || len(body.Metrics) > 0 {
+		pr.Heartbeat = &body.Heartbeat
+	}
+
+	if len(pr.Source) == 0 {
+		pr.Source = body.Source
+	}
+
+	requested := body.TTL > 0
+	if v := request.Form.Get(TTLParameter); len(v) > 0 {
+		requested = true
+		pr.TTL, err = time.ParseDuration(v)
+	}
+
+	switch {
+	case err != nil || !requested:
+		// nothing further to check
+
+	case pr.TTL <= 0:
+		err = fmt.Errorf("ttl %s is not positive", pr.TTL)
+
+	default:
+		if tc, ok := ph.Postponer.(TTLChecker); ok && pr.TTL < tc.MinTTL() {
+			err = fmt.Errorf("ttl %s is less than the minimum of %s", pr.TTL, tc.MinTTL())
+		}
+	}
+
+	return
+}
+
 func (ph PostponeHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	pr, err := ph.postponeRequest(request)
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(err.Error()))
+		return
+	}
+
+	if !ph.Postponer.Postpone(pr) {
+		response.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	// an unhealthy heartbeat was received, but did not postpone the switch
+	if hc, ok := ph.Postponer.(HeartbeatChecker); ok {
+		if failed := hc.FailedRules(pr.Heartbeat); len(failed) > 0 {
+			response.WriteHeader(http.StatusUnprocessableEntity)
+			response.Write([]byte("unhealthy heartbeat [failed=" + failed.String() + "]"))
+			return
+		}
+	}
+
+	response.WriteHeader(http.StatusOK)
+}
+
+// RearmHandler re-arms a tripped switch.  If the switch is active but has not tripped,
+// this handler returns http.StatusConflict.  If the switch is not active, this handler
+// returns http.StatusServiceUnavailable.
+type RearmHandler struct {
+	Rearmer Rearmer
+}
+
+func (rh RearmHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if err := rh.Rearmer.Rearm(); err != nil {
+		writeSwitchError(response, err)
+	} else {
+		response.WriteHeader(http.StatusOK)
+	}
+}
+
+// writeSwitchError writes the HTTP response for an error from a Switch operation.
+func writeSwitchError(response http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotActive):
+		response.WriteHeader(http.StatusServiceUnavailable)
+
+	case errors.Is(err, ErrPauseReason), errors.Is(err, ErrPauseDuration), errors.Is(err, ErrPauseTooLong),
+		errors.Is(err, ErrTriggerReason), errors.Is(err, ErrInvalidConfirmation):
+		response.WriteHeader(http.StatusBadRequest)
+
+	default:
+		response.WriteHeader(http.StatusConflict)
+	}
+
+	response.Write([]byte(err.Error()))
+}
+
+// PauseHandler pauses a switch.  The reason and duration are taken from the
+// ReasonParameter and DurationParameter, both of which are required.
+type PauseHandler struct {
+	Pauser Pauser
+}
+
+func (ph PauseHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
 	err := request.ParseForm()
 	if err != nil {
 		response.WriteHeader(http.StatusBadRequest)
@@ -53,26 +270,451 @@ func (ph PostponeHandler) ServeHTTP(response http.ResponseWriter, request *http.
 		return
 	}
 
-	pr := PostponeRequest{
-		Source:     request.Form.Get(SourceParameter),
+	pr := PauseRequest{
+		Reason:     request.Form.Get(ReasonParameter),
 		RemoteAddr: request.RemoteAddr,
 	}
 
-	if ph.Postponer.Postpone(pr) {
+	if d := request.Form.Get(DurationParameter); len(d) > 0 {
+		if pr.Duration, err = time.ParseDuration(d); err != nil {
+			response.WriteHeader(http.StatusBadRequest)
+			response.Write([]byte(err.Error()))
+			return
+		}
+	}
+
+	if err = ph.Pauser.Pause(pr); err != nil {
+		writeSwitchError(response, err)
+	} else {
 		response.WriteHeader(http.StatusOK)
+	}
+}
+
+// ResumeHandler ends the pause of a switch.
+type ResumeHandler struct {
+	Pauser Pauser
+}
+
+func (rh ResumeHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if err := rh.Pauser.Resume(); err != nil {
+		writeSwitchError(response, err)
 	} else {
-		response.WriteHeader(http.StatusServiceUnavailable)
+		response.WriteHeader(http.StatusOK)
+	}
+}
+
+// TriggerConfirmation is the response to a manual trigger that awaits confirmation.
+type TriggerConfirmation struct {
+	// Confirm is the token that confirms the trigger.
+	Confirm string `json:"confirm"`
+
+	// Expires is the time after which the token is no longer valid.
+	Expires time.Time `json:"expires"`
+}
+
+// pendingTrigger is a manual trigger awaiting confirmation.
+type pendingTrigger struct {
+	triggerer Triggerer
+	request   TriggerRequest
+	expires   time.Time
+}
+
+// Confirmations holds the manual triggers that are awaiting confirmation.  A single
+// Confirmations may be shared by the TriggerHandlers of several switches.
+type Confirmations struct {
+	ttl   time.Duration
+	clock chronon.Clock
+
+	lock    sync.Mutex
+	pending map[string]pendingTrigger
+}
+
+// NewConfirmations creates a Confirmations whose tokens are valid for the given
+// duration.  If ttl is nonpositive, DefaultConfirmTTL is used.  If the clock is
+// nil, the system clock is used.
+func NewConfirmations(ttl time.Duration, clock chronon.Clock) *Confirmations {
+	if ttl <= 0 {
+		ttl = DefaultConfirmTTL
+	}
+
+	if clock == nil {
+		clock = chronon.SystemClock()
+	}
+
+	return &Confirmations{
+		ttl:     ttl,
+		clock:   clock,
+		pending: make(map[string]pendingTrigger),
 	}
 }
 
+// Add records a manual trigger of the given Triggerer and returns the token that
+// confirms it.
+func (c *Confirmations) Add(t Triggerer, tr TriggerRequest) TriggerConfirmation {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := c.clock.Now()
+	for token, p := range c.pending {
+		if !now.Before(p.expires) {
+			delete(c.pending, token)
+		}
+	}
+
+	tc := TriggerConfirmation{
+		Confirm: rand.Text(),
+		Expires: now.Add(c.ttl),
+	}
+
+	c.pending[tc.Confirm] = pendingTrigger{
+		triggerer: t,
+		request:   tr,
+		expires:   tc.Expires,
+	}
+
+	return tc
+}
+
+// Take removes and returns the manual trigger confirmed by the given token.  This
+// method returns false if the token is unknown or expired, or if the trigger was
+// requested by a different source or for a different Triggerer.  A token can only
+// be taken once.
+func (c *Confirmations) Take(token string, t Triggerer, source string) (tr TriggerRequest, ok bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	p, exists := c.pending[token]
+	if !exists || p.triggerer != t || p.request.Source != source {
+		return
+	}
+
+	delete(c.pending, token)
+	if c.clock.Now().Before(p.expires) {
+		tr, ok = p.request, true
+	}
+
+	return
+}
+
+// TriggerHandler manually triggers a switch in two steps.  The first request must
+// carry the ReasonParameter, and receives http.StatusAccepted along with a
+// TriggerConfirmation.  The switch is only triggered by a second request that carries
+// the confirmation token in the ConfirmParameter.  The second request must come from
+// the same caller, as reported by Principal, before the token expires.
+type TriggerHandler struct {
+	Triggerer     Triggerer
+	Confirmations *Confirmations
+}
+
+func (th TriggerHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	err := request.ParseForm()
+	if err != nil {
+		response.WriteHeader(http.StatusBadRequest)
+		response.Write([]byte(err.Error()))
+		return
+	}
+
+	source, _ := Principal(request.Context())
+	if token := request.Form.Get(ConfirmParameter); len(token) > 0 {
+		tr, ok := th.Confirmations.Take(token, th.Triggerer, source)
+		if !ok {
+			writeSwitchError(response, ErrInvalidConfirmation)
+			return
+		}
+
+		// record the address that actually confirmed the trigger
+		tr.RemoteAddr = request.RemoteAddr
+		if err = th.Triggerer.Trigger(tr); err != nil {
+			writeSwitchError(response, err)
+		} else {
+			response.WriteHeader(http.StatusOK)
+		}
+
+		return
+	}
+
+	tr := TriggerRequest{
+		Source:     source,
+		Reason:     request.Form.Get(ReasonParameter),
+		RemoteAddr: request.RemoteAddr,
+	}
+
+	if len(tr.Reason) == 0 {
+		writeSwitchError(response, ErrTriggerReason)
+		return
+	}
+
+	data, _ := json.Marshal(th.Confirmations.Add(th.Triggerer, tr))
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusAccepted)
+	response.Write(data)
+}
+
+// DeactivateHandler stands a switch down without triggering it, and writes the
+// switch's final Status as JSON.  The caller is identified by Principal.
+type DeactivateHandler struct {
+	Deactivator Deactivator
+}
+
+func (dh DeactivateHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	source, _ := Principal(request.Context())
+	st, err := dh.Deactivator.StandDown(DeactivateRequest{
+		Source:     source,
+		RemoteAddr: request.RemoteAddr,
+	})
+
+	if err != nil {
+		writeSwitchError(response, err)
+		return
+	}
+
+	writeStatus(response, st)
+}
+
+// AbortHandler stops the actions that a switch is running.  The switch remains
+// active.  If the switch is not running any actions, this handler returns
+// http.StatusConflict.  The caller is identified by Principal.
+type AbortHandler struct {
+	Logger  Logger
+	Aborter Aborter
+}
+
+func (ah AbortHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	source, _ := Principal(request.Context())
+	ah.Logger.Printf("abort requested [source=%s] [remoteaddr=%s]", source, request.RemoteAddr)
+	if ah.Aborter.Abort() {
+		response.WriteHeader(http.StatusOK)
+	} else {
+		response.WriteHeader(http.StatusConflict)
+		response.Write([]byte("No actions are running"))
+	}
+}
+
+// writeStatus writes a Status as the JSON body of a response.
+func writeStatus(response http.ResponseWriter, st Status) {
+	data, err := json.Marshal(st)
+	if err != nil {
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte(err.Error()))
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusOK)
+	response.Write(data)
+}
+
+// StatusHandler writes a switch's Status as JSON.
+type StatusHandler struct {
+	StatusReporter StatusReporter
+}
+
+func (sh StatusHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	writeStatus(response, sh.StatusReporter.Status())
+}
+
+// EventsHandler streams switch events to a client as server-sent events.  Each
+// event's name is its type, and its data is the JSON form of the Event.  The
+// stream ends when the client disconnects, when the client falls too far behind,
+// or when the Events is closed.
+type EventsHandler struct {
+	Events *Events
+}
+
+func (eh EventsHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	flusher, ok := response.(http.Flusher)
+	if !ok {
+		response.WriteHeader(http.StatusInternalServerError)
+		response.Write([]byte("streaming is not supported"))
+		return
+	}
+
+	events, cancel := eh.Events.Subscribe()
+	defer cancel()
+
+	response.Header().Set("Content-Type", "text/event-stream")
+	response.Header().Set("Cache-Control", "no-cache")
+	response.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+
+			if _, err = fmt.Fprintf(response, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+				return
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+// SwitchHandler dispatches requests to a handler for a named Switch in a Registry.
+// The switch's name is taken from the SwitchNameVariable path variable.  If no
+// such switch exists, this handler returns http.StatusNotFound.
+type SwitchHandler struct {
+	Registry *Registry
+	Handler  func(*Switch) http.Handler
+}
+
+func (sh SwitchHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	s, ok := sh.Registry.Get(mux.Vars(request)[SwitchNameVariable])
+	if !ok {
+		response.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	sh.Handler(s).ServeHTTP(response, request)
+}
+
+// RouterIn describes the dependencies necessary for creating the HTTP router.
+type RouterIn struct {
+	fx.In
+
+	Logger      Logger
+	Postponer   Postponer
+	Rearmer     Rearmer        `optional:"true"`
+	Pauser      Pauser         `optional:"true"`
+	Status      StatusReporter `optional:"true"`
+	Events      *Events        `optional:"true"`
+	Triggerer   Triggerer      `optional:"true"`
+	Deactivator Deactivator    `optional:"true"`
+	Aborter     Aborter        `optional:"true"`
+	Registry    *Registry      `optional:"true"`
+	Clock       chronon.Clock  `optional:"true"`
+
+	// Authenticator guards the administrative endpoints.  If nil, those
+	// endpoints are not available.
+	Authenticator *Authenticator
+}
+
 func provideHTTP() fx.Option {
 	return fx.Options(
+		provideAuthenticator(),
 		fx.Provide(
-			func(logger Logger, p Postponer) *mux.Router {
+			func(in RouterIn) *mux.Router {
 				r := mux.NewRouter()
-				r.Handle(PostponePath, PostponeHandler{Postponer: p}).Methods("PUT")
-				r.NotFoundHandler = notFoundHandler{l: logger}
-				r.MethodNotAllowedHandler = methodNotAllowedHandler{l: logger}
+
+				// endpoints that suspend or re-arm a switch require a bearer token
+				// whenever administrative tokens are configured
+				guarded := func(h http.Handler) http.Handler {
+					if in.Authenticator != nil {
+						h = AuthHandler{Logger: in.Logger, Authenticator: in.Authenticator, Handler: h}
+					}
+
+					return h
+				}
+
+				r.Handle(PostponePath, PostponeHandler{Postponer: in.Postponer}).Methods("PUT")
+				if in.Rearmer != nil {
+					r.Handle(RearmPath, guarded(RearmHandler{Rearmer: in.Rearmer})).Methods("PUT")
+				}
+
+				if in.Status != nil {
+					r.Handle(StatusPath, StatusHandler{StatusReporter: in.Status}).Methods("GET")
+				}
+
+				if in.Events != nil {
+					r.Handle(EventsPath, EventsHandler{Events: in.Events}).Methods("GET")
+				}
+
+				if in.Pauser != nil {
+					r.Handle(PausePath, guarded(PauseHandler{Pauser: in.Pauser})).Methods("PUT")
+					r.Handle(ResumePath, guarded(ResumeHandler{Pauser: in.Pauser})).Methods("PUT")
+				}
+
+				if in.Registry != nil {
+					r.Handle(SwitchPostponePath, SwitchHandler{
+						Registry: in.Registry,
+						Handler: func(s *Switch) http.Handler {
+							return PostponeHandler{Postponer: s}
+						},
+					}).Methods("PUT")
+
+					r.Handle(SwitchRearmPath, guarded(SwitchHandler{
+						Registry: in.Registry,
+						Handler: func(s *Switch) http.Handler {
+							return RearmHandler{Rearmer: s}
+						},
+					})).Methods("PUT")
+
+					r.Handle(SwitchStatusPath, SwitchHandler{
+						Registry: in.Registry,
+						Handler: func(s *Switch) http.Handler {
+							return StatusHandler{StatusReporter: s}
+						},
+					}).Methods("GET")
+
+					r.Handle(SwitchPausePath, guarded(SwitchHandler{
+						Registry: in.Registry,
+						Handler: func(s *Switch) http.Handler {
+							return PauseHandler{Pauser: s}
+						},
+					})).Methods("PUT")
+
+					r.Handle(SwitchResumePath, guarded(SwitchHandler{
+						Registry: in.Registry,
+						Handler: func(s *Switch) http.Handler {
+							return ResumeHandler{Pauser: s}
+						},
+					})).Methods("PUT")
+				}
+
+				if in.Authenticator != nil {
+					confirmations := NewConfirmations(DefaultConfirmTTL, in.Clock)
+					if in.Triggerer != nil {
+						r.Handle(TriggerPath, guarded(TriggerHandler{
+							Triggerer:     in.Triggerer,
+							Confirmations: confirmations,
+						})).Methods("POST")
+					}
+
+					if in.Deactivator != nil {
+						r.Handle(DeactivatePath, guarded(DeactivateHandler{Deactivator: in.Deactivator})).Methods("POST")
+					}
+
+					if in.Aborter != nil {
+						r.Handle(AbortPath, guarded(AbortHandler{Logger: in.Logger, Aborter: in.Aborter})).Methods("PUT")
+					}
+
+					if in.Registry != nil {
+						r.Handle(SwitchTriggerPath, guarded(SwitchHandler{
+							Registry: in.Registry,
+							Handler: func(s *Switch) http.Handler {
+								return TriggerHandler{Triggerer: s, Confirmations: confirmations}
+							},
+						})).Methods("POST")
+
+						r.Handle(SwitchDeactivatePath, guarded(SwitchHandler{
+							Registry: in.Registry,
+							Handler: func(s *Switch) http.Handler {
+								return DeactivateHandler{Deactivator: s}
+							},
+						})).Methods("POST")
+
+						r.Handle(SwitchAbortPath, guarded(SwitchHandler{
+							Registry: in.Registry,
+							Handler: func(s *Switch) http.Handler {
+								return AbortHandler{Logger: in.Logger, Aborter: s}
+							},
+						})).Methods("PUT")
+					}
+				}
+
+				r.NotFoundHandler = notFoundHandler{l: in.Logger}
+				r.MethodNotAllowedHandler = methodNotAllowedHandler{l: in.Logger}
 
 				return r
 			},
@@ -92,7 +734,13 @@ func provideHTTP() fx.Option {
 			},
 		),
 		fx.Invoke(
-			func(logger Logger, l fx.Lifecycle, s fx.Shutdowner, server *http.Server) {
+			func(in RouterIn, l fx.Lifecycle, s fx.Shutdowner, server *http.Server) {
+				logger := in.Logger
+				if in.Events != nil {
+					// event streams never go idle, so end them when the server shuts down
+					server.RegisterOnShutdown(in.Events.Close)
+				}
+
 				l.Append(fx.Hook{
 					OnStart: func(ctx context.Context) error {
 						var lc net.ListenConfig
@@ -105,6 +753,11 @@ func provideHTTP() fx.Option {
 						// this handles cases where port 0 is used to bind to the first available port
 						server.Addr = l.Addr().String()
 						logger.Printf("PUT http://%s%s to postpone triggering actions", server.Addr, PostponePath)
+						if in.Registry != nil {
+							for _, name := range in.Registry.Names() {
+								logger.Printf("PUT http://%s/switches/%s/postpone to postpone triggering actions", server.Addr, name)
+							}
+						}
 
 						go func() {
 							defer s.Shutdown()
@@ -4,15 +4,21 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/fx"
@@ -134,10 +140,631 @@ func (suite *PostponeHandlerSuite) TestParseFormError() {
 	p.AssertExpectations(suite.T())
 }
 
+// checkingPostponer is a Postponer which checks heartbeats against rules.
+type checkingPostponer struct {
+	*mockPostponer
+	rules Rules
+}
+
+func (cp checkingPostponer) FailedRules(hb *Heartbeat) Rules {
+	return cp.rules.Failed(hb)
+}
+
+func (suite *PostponeHandlerSuite) TestUnhealthy() {
+	rule, err := ParseRule(`status == "ok"`)
+	suite.Require().NoError(err)
+
+	testData := []struct {
+		status         string
+		expectedStatus int
+	}{
+		{"ok", http.StatusOK},
+		{"degraded", http.StatusUnprocessableEntity},
+	}
+
+	for _, testCase := range testData {
+		suite.Run(testCase.status, func() {
+			var (
+				p        = new(mockPostponer)
+				ph       = PostponeHandler{Postponer: checkingPostponer{mockPostponer: p, rules: Rules{rule}}}
+				response = httptest.NewRecorder()
+				request  = suite.newRequest("test", "", strings.NewReader(`{"status": "`+testCase.status+`"}`))
+			)
+
+			request.Header.Set("Content-Type", "application/json")
+			p.ExpectPostpone(PostponeRequest{
+				Source:    "test",
+				Heartbeat: &Heartbeat{Status: testCase.status},
+			}).Return(true).Once()
+
+			ph.ServeHTTP(response, request)
+			suite.Equal(testCase.expectedStatus, response.Code)
+			if testCase.expectedStatus != http.StatusOK {
+				suite.Contains(response.Body.String(), rule.String())
+			}
+
+			p.AssertExpectations(suite.T())
+		})
+	}
+}
+
+func (suite *PostponeHandlerSuite) TestTTL() {
+	testData := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		expected    PostponeRequest
+	}{
+		{
+			name:     "Query",
+			target:   "/test-postpone?source=test&ttl=20m",
+			expected: PostponeRequest{Source: "test", TTL: 20 * time.Minute},
+		},
+		{
+			name:        "Form",
+			target:      "/test-postpone",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "source=test&ttl=20m",
+			expected:    PostponeRequest{Source: "test", TTL: 20 * time.Minute},
+		},
+		{
+			name:        "JSON",
+			target:      "/test-postpone",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"source": "test", "ttl": "20m"}`,
+			expected:    PostponeRequest{Source: "test", TTL: 20 * time.Minute},
+		},
+		{
+			name:        "Heartbeat",
+			target:      "/test-postpone",
+			contentType: "application/json",
+			body:        `{"source": "test", "status": "ok", "metrics": {"queue_depth": 12, "region": "east"}}`,
+			expected: PostponeRequest{
+				Source: "test",
+				Heartbeat: &Heartbeat{
+					Status:  "ok",
+					Metrics: map[string]any{"queue_depth": 12.0, "region": "east"},
+				},
+			},
+		},
+		{
+			name:        "EmptyJSON",
+			target:      "/test-postpone?source=test",
+			contentType: "application/json",
+			expected:    PostponeRequest{Source: "test"},
+		},
+		{
+			name:        "JSONSeconds",
+			target:      "/test-postpone",
+			contentType: "application/json",
+			body:        `{"source": "test", "ttl": 1200}`,
+			expected:    PostponeRequest{Source: "test", TTL: 20 * time.Minute},
+		},
+		{
+			name:        "FormOverridesJSON",
+			target:      "/test-postpone?ttl=5m",
+			contentType: "application/json",
+			body:        `{"source": "test", "ttl": "20m"}`,
+			expected:    PostponeRequest{Source: "test", TTL: 5 * time.Minute},
+		},
+	}
+
+	for _, testCase := range testData {
+		suite.Run(testCase.name, func() {
+			var (
+				p        = new(mockPostponer)
+				ph       = PostponeHandler{Postponer: p}
+				request  = httptest.NewRequest("PUT", testCase.target, strings.NewReader(testCase.body))
+				response = httptest.NewRecorder()
+			)
+
+			request.RemoteAddr = ""
+			if len(testCase.contentType) > 0 {
+				request.Header.Set("Content-Type", testCase.contentType)
+			}
+
+			p.ExpectPostpone(testCase.expected).Return(true).Once()
+			ph.ServeHTTP(response, request)
+			suite.Equal(http.StatusOK, response.Code)
+			p.AssertExpectations(suite.T())
+		})
+	}
+}
+
+// boundedPostponer is a Postponer with a minimum TTL.
+type boundedPostponer struct {
+	*mockPostponer
+	minTTL time.Duration
+}
+
+func (bp boundedPostponer) MinTTL() time.Duration {
+	return bp.minTTL
+}
+
+func (suite *PostponeHandlerSuite) TestBadTTL() {
+	testData := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+	}{
+		{name: "Invalid", target: "/test-postpone?ttl=nosuch"},
+		{name: "Negative", target: "/test-postpone?ttl=-1m"},
+		{name: "Zero", target: "/test-postpone?ttl=0s"},
+		{name: "TooSmall", target: "/test-postpone?ttl=1ns"},
+		{name: "JSONZero", target: "/test-postpone", contentType: "application/json", body: `{"ttl": 0}`},
+		{name: "JSONNegative", target: "/test-postpone", contentType: "application/json", body: `{"ttl": -60}`},
+		{name: "JSONTooSmall", target: "/test-postpone", contentType: "application/json", body: `{"ttl": 0.5}`},
+		{name: "BadJSON", target: "/test-postpone", contentType: "application/json", body: `{`},
+		{name: "BadJSONTTL", target: "/test-postpone", contentType: "application/json", body: `{"ttl": "nosuch"}`},
+	}
+
+	for _, testCase := range testData {
+		suite.Run(testCase.name, func() {
+			var (
+				p        = new(mockPostponer)
+				ph       = PostponeHandler{Postponer: boundedPostponer{mockPostponer: p, minTTL: time.Second}}
+				request  = httptest.NewRequest("PUT", testCase.target, strings.NewReader(testCase.body))
+				response = httptest.NewRecorder()
+			)
+
+			if len(testCase.contentType) > 0 {
+				request.Header.Set("Content-Type", testCase.contentType)
+			}
+
+			ph.ServeHTTP(response, request)
+			suite.Equal(http.StatusBadRequest, response.Code)
+			suite.NotEmpty(response.Body.String())
+			p.AssertExpectations(suite.T())
+		})
+	}
+}
+
 func TestPostponeHandler(t *testing.T) {
 	suite.Run(t, new(PostponeHandlerSuite))
 }
 
+type RearmHandlerSuite struct {
+	DMSSuite
+}
+
+func (suite *RearmHandlerSuite) TestServeHTTP() {
+	testData := []struct {
+		err            error
+		expectedStatus int
+	}{
+		{nil, http.StatusOK},
+		{ErrNotTripped, http.StatusConflict},
+		{ErrNotActive, http.StatusServiceUnavailable},
+	}
+
+	for _, testCase := range testData {
+		suite.Run(fmt.Sprintf("%v", testCase.err), func() {
+			var (
+				r        = new(mockRearmer)
+				rh       = RearmHandler{Rearmer: r}
+				response = httptest.NewRecorder()
+			)
+
+			r.ExpectRearm().Return(testCase.err).Once()
+			rh.ServeHTTP(response, httptest.NewRequest("PUT", RearmPath, nil))
+			suite.Equal(testCase.expectedStatus, response.Code)
+			r.AssertExpectations(suite.T())
+		})
+	}
+}
+
+func TestRearmHandler(t *testing.T) {
+	suite.Run(t, new(RearmHandlerSuite))
+}
+
+type PauseHandlerSuite struct {
+	DMSSuite
+}
+
+func (suite *PauseHandlerSuite) TestServeHTTP() {
+	testData := []struct {
+		err            error
+		expectedStatus int
+	}{
+		{nil, http.StatusOK},
+		{ErrNotArmed, http.StatusConflict},
+		{ErrPauseReason, http.StatusBadRequest},
+		{ErrPauseTooLong, http.StatusBadRequest},
+		{ErrNotActive, http.StatusServiceUnavailable},
+	}
+
+	for _, testCase := range testData {
+		suite.Run(fmt.Sprintf("%v", testCase.err), func() {
+			var (
+				p        = new(mockPauser)
+				ph       = PauseHandler{Pauser: p}
+				response = httptest.NewRecorder()
+				request  = httptest.NewRequest("PUT", PausePath+"?reason=deploy&duration=15m", nil)
+			)
+
+			request.RemoteAddr = "127.0.0.1:1234"
+			p.ExpectPause(PauseRequest{
+				Reason:     "deploy",
+				Duration:   15 * time.Minute,
+				RemoteAddr: "127.0.0.1:1234",
+			}).Return(testCase.err).Once()
+
+			ph.ServeHTTP(response, request)
+			suite.Equal(testCase.expectedStatus, response.Code)
+			p.AssertExpectations(suite.T())
+		})
+	}
+}
+
+func (suite *PauseHandlerSuite) TestBadDuration() {
+	var (
+		p        = new(mockPauser)
+		ph       = PauseHandler{Pauser: p}
+		response = httptest.NewRecorder()
+	)
+
+	ph.ServeHTTP(response, httptest.NewRequest("PUT", PausePath+"?reason=deploy&duration=nosuch", nil))
+	suite.Equal(http.StatusBadRequest, response.Code)
+	suite.NotEmpty(response.Body.String())
+	p.AssertExpectations(suite.T())
+}
+
+func (suite *PauseHandlerSuite) TestResume() {
+	testData := []struct {
+		err            error
+		expectedStatus int
+	}{
+		{nil, http.StatusOK},
+		{ErrNotPaused, http.StatusConflict},
+		{ErrNotActive, http.StatusServiceUnavailable},
+	}
+
+	for _, testCase := range testData {
+		suite.Run(fmt.Sprintf("%v", testCase.err), func() {
+			var (
+				p        = new(mockPauser)
+				rh       = ResumeHandler{Pauser: p}
+				response = httptest.NewRecorder()
+			)
+
+			p.ExpectResume().Return(testCase.err).Once()
+			rh.ServeHTTP(response, httptest.NewRequest("PUT", ResumePath, nil))
+			suite.Equal(testCase.expectedStatus, response.Code)
+			p.AssertExpectations(suite.T())
+		})
+	}
+}
+
+func TestPauseHandler(t *testing.T) {
+	suite.Run(t, new(PauseHandlerSuite))
+}
+
+type TriggerHandlerSuite struct {
+	DMSSuite
+}
+
+// serve sends a manual trigger request, with the given form, from the given principal.
+func (suite *TriggerHandlerSuite) serve(th TriggerHandler, principal string, form url.Values) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("POST", TriggerPath, strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.RemoteAddr = "127.0.0.1:1234"
+	if len(principal) > 0 {
+		request = request.WithContext(context.WithValue(request.Context(), principalKey{}, principal))
+	}
+
+	response := httptest.NewRecorder()
+	th.ServeHTTP(response, request)
+	return response
+}
+
+// request sends the first step of a manual trigger and returns the confirmation.
+func (suite *TriggerHandlerSuite) request(th TriggerHandler, principal, reason string) TriggerConfirmation {
+	response := suite.serve(th, principal, url.Values{ReasonParameter: {reason}})
+	suite.Require().Equal(http.StatusAccepted, response.Code)
+	suite.Equal("application/json", response.Header().Get("Content-Type"))
+
+	var tc TriggerConfirmation
+	suite.Require().NoError(json.Unmarshal(response.Body.Bytes(), &tc))
+	suite.Require().NotEmpty(tc.Confirm)
+	return tc
+}
+
+func (suite *TriggerHandlerSuite) TestConfirmed() {
+	var (
+		t     = new(mockTriggerer)
+		clock = suite.clock()
+		th    = TriggerHandler{Triggerer: t, Confirmations: NewConfirmations(time.Minute, clock)}
+	)
+
+	tc := suite.request(th, "ops", "runbook test")
+	suite.True(tc.Expires.Equal(clock.Now().Add(time.Minute)))
+
+	t.ExpectTrigger(TriggerRequest{Source: "ops", Reason: "runbook test", RemoteAddr: "127.0.0.1:1234"}).Return(nil).Once()
+	suite.Equal(http.StatusOK, suite.serve(th, "ops", url.Values{ConfirmParameter: {tc.Confirm}}).Code)
+
+	// a token can only be used once
+	suite.Equal(http.StatusBadRequest, suite.serve(th, "ops", url.Values{ConfirmParameter: {tc.Confirm}}).Code)
+	t.AssertExpectations(suite.T())
+}
+
+func (suite *TriggerHandlerSuite) TestNoReason() {
+	var (
+		t  = new(mockTriggerer)
+		th = TriggerHandler{Triggerer: t, Confirmations: NewConfirmations(0, nil)}
+	)
+
+	suite.Equal(http.StatusBadRequest, suite.serve(th, "ops", url.Values{}).Code)
+	t.AssertExpectations(suite.T())
+}
+
+func (suite *TriggerHandlerSuite) TestInvalidConfirmation() {
+	var (
+		t             = new(mockTriggerer)
+		other         = new(mockTriggerer)
+		clock         = suite.clock()
+		confirmations = NewConfirmations(time.Minute, clock)
+		th            = TriggerHandler{Triggerer: t, Confirmations: confirmations}
+	)
+
+	suite.Run("Unknown", func() {
+		suite.Equal(http.StatusBadRequest, suite.serve(th, "ops", url.Values{ConfirmParameter: {"nosuch"}}).Code)
+	})
+
+	suite.Run("DifferentCaller", func() {
+		tc := suite.request(th, "ops", "test")
+		suite.Equal(http.StatusBadRequest, suite.serve(th, "intruder", url.Values{ConfirmParameter: {tc.Confirm}}).Code)
+	})
+
+	suite.Run("DifferentSwitch", func() {
+		tc := suite.request(th, "ops", "test")
+		otherHandler := TriggerHandler{Triggerer: other, Confirmations: confirmations}
+		suite.Equal(http.StatusBadRequest, suite.serve(otherHandler, "ops", url.Values{ConfirmParameter: {tc.Confirm}}).Code)
+	})
+
+	suite.Run("Expired", func() {
+		tc := suite.request(th, "ops", "test")
+		clock.Add(time.Minute)
+		suite.Equal(http.StatusBadRequest, suite.serve(th, "ops", url.Values{ConfirmParameter: {tc.Confirm}}).Code)
+	})
+
+	t.AssertExpectations(suite.T())
+	other.AssertExpectations(suite.T())
+}
+
+func (suite *TriggerHandlerSuite) TestTriggerError() {
+	testData := []struct {
+		err            error
+		expectedStatus int
+	}{
+		{ErrNotActive, http.StatusServiceUnavailable},
+		{ErrTripped, http.StatusConflict},
+		{ErrHeld, http.StatusConflict},
+	}
+
+	for _, testCase := range testData {
+		suite.Run(testCase.err.Error(), func() {
+			var (
+				t  = new(mockTriggerer)
+				th = TriggerHandler{Triggerer: t, Confirmations: NewConfirmations(0, nil)}
+			)
+
+			tc := suite.request(th, "ops", "test")
+			t.ExpectTrigger(mock.Anything).Return(testCase.err).Once()
+			suite.Equal(testCase.expectedStatus, suite.serve(th, "ops", url.Values{ConfirmParameter: {tc.Confirm}}).Code)
+			t.AssertExpectations(suite.T())
+		})
+	}
+}
+
+func TestTriggerHandler(t *testing.T) {
+	suite.Run(t, new(TriggerHandlerSuite))
+}
+
+type DeactivateHandlerSuite struct {
+	DMSSuite
+}
+
+func (suite *DeactivateHandlerSuite) serve(dh DeactivateHandler) *httptest.ResponseRecorder {
+	request := httptest.NewRequest("POST", DeactivatePath, nil)
+	request.RemoteAddr = "127.0.0.1:1234"
+	request = request.WithContext(context.WithValue(request.Context(), principalKey{}, "ops"))
+
+	response := httptest.NewRecorder()
+	dh.ServeHTTP(response, request)
+	return response
+}
+
+func (suite *DeactivateHandlerSuite) TestServeHTTP() {
+	var (
+		d  = new(mockDeactivator)
+		dh = DeactivateHandler{Deactivator: d}
+	)
+
+	d.ExpectStandDown(DeactivateRequest{Source: "ops", RemoteAddr: "127.0.0.1:1234"}).
+		Return(Status{State: StateDeactivated, TTL: Duration(time.Minute), Actions: []string{}}, nil).
+		Once()
+
+	response := suite.serve(dh)
+	suite.Equal(http.StatusOK, response.Code)
+	suite.Equal("application/json", response.Header().Get("Content-Type"))
+	suite.JSONEq(
+		`{"state": "deactivated", "ttl": "1m0s", "maxMisses": 0, "misses": 0, "actions": []}`,
+		response.Body.String(),
+	)
+
+	d.AssertExpectations(suite.T())
+}
+
+func (suite *DeactivateHandlerSuite) TestNotActive() {
+	var (
+		d  = new(mockDeactivator)
+		dh = DeactivateHandler{Deactivator: d}
+	)
+
+	d.ExpectStandDown(mock.Anything).Return(Status{}, ErrNotActive).Once()
+	suite.Equal(http.StatusServiceUnavailable, suite.serve(dh).Code)
+	d.AssertExpectations(suite.T())
+}
+
+func TestDeactivateHandler(t *testing.T) {
+	suite.Run(t, new(DeactivateHandlerSuite))
+}
+
+type AbortHandlerSuite struct {
+	DMSSuite
+}
+
+func (suite *AbortHandlerSuite) TestServeHTTP() {
+	testData := []struct {
+		aborted        bool
+		expectedStatus int
+	}{
+		{true, http.StatusOK},
+		{false, http.StatusConflict},
+	}
+
+	for _, testCase := range testData {
+		suite.Run(fmt.Sprintf("aborted=%t", testCase.aborted), func() {
+			var (
+				a        = new(mockAborter)
+				ah       = AbortHandler{Logger: suite.logger, Aborter: a}
+				response = httptest.NewRecorder()
+			)
+
+			a.ExpectAbort().Return(testCase.aborted).Once()
+			ah.ServeHTTP(response, httptest.NewRequest("PUT", AbortPath, nil))
+			suite.Equal(testCase.expectedStatus, response.Code)
+			a.AssertExpectations(suite.T())
+		})
+	}
+}
+
+func TestAbortHandler(t *testing.T) {
+	suite.Run(t, new(AbortHandlerSuite))
+}
+
+type StatusHandlerSuite struct {
+	DMSSuite
+}
+
+func (suite *StatusHandlerSuite) TestServeHTTP() {
+	var (
+		mockActions = newMockActions(1)
+		cfg, _      = suite.switchConfig(time.Minute, 2, mockActions.actions()...)
+		sh          = StatusHandler{StatusReporter: suite.newSwitch(cfg)}
+		response    = httptest.NewRecorder()
+	)
+
+	sh.ServeHTTP(response, httptest.NewRequest("GET", StatusPath, nil))
+	suite.Equal(http.StatusOK, response.Code)
+	suite.Equal("application/json", response.Header().Get("Content-Type"))
+	suite.JSONEq(
+		`{"state": "inactive", "ttl": "1m0s", "maxMisses": 2, "misses": 0, "actions": ["action[0]"]}`,
+		response.Body.String(),
+	)
+}
+
+func TestStatusHandler(t *testing.T) {
+	suite.Run(t, new(StatusHandlerSuite))
+}
+
+type EventsHandlerSuite struct {
+	DMSSuite
+}
+
+func (suite *EventsHandlerSuite) TestServeHTTP() {
+	var (
+		events = NewEvents(0)
+		server = httptest.NewServer(EventsHandler{Events: events})
+	)
+
+	defer server.Close()
+	response, err := http.Get(server.URL + EventsPath)
+	suite.Require().NoError(err)
+	defer response.Body.Close()
+
+	// the response headers are only sent once the handler has subscribed
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.Equal("text/event-stream", response.Header.Get("Content-Type"))
+
+	events.Publish(Event{
+		Type: EventPostpone,
+		Time: time.Date(2026, time.January, 4, 2, 0, 0, 0, time.UTC),
+		Data: PostponeEvent{Source: "test"},
+	})
+
+	reader := bufio.NewReader(response.Body)
+	line, err := reader.ReadString('\n')
+	suite.Require().NoError(err)
+	suite.Equal("event: postpone\n", line)
+
+	line, err = reader.ReadString('\n')
+	suite.Require().NoError(err)
+	suite.Require().True(strings.HasPrefix(line, "data: "))
+	suite.JSONEq(
+		`{"type": "postpone", "time": "2026-01-04T02:00:00Z", "data": {"source": "test"}}`,
+		strings.TrimPrefix(line, "data: "),
+	)
+
+	line, err = reader.ReadString('\n')
+	suite.Require().NoError(err)
+	suite.Equal("\n", line)
+
+	// closing the events ends the stream
+	events.Close()
+	_, err = reader.ReadString('\n')
+	suite.ErrorIs(err, io.EOF)
+}
+
+func TestEventsHandler(t *testing.T) {
+	suite.Run(t, new(EventsHandlerSuite))
+}
+
+type SwitchHandlerSuite struct {
+	DMSSuite
+}
+
+func (suite *SwitchHandlerSuite) serve(r *Registry, name string) *http.Response {
+	var (
+		router = mux.NewRouter()
+		called *Switch
+	)
+
+	router.Handle(SwitchPostponePath, SwitchHandler{
+		Registry: r,
+		Handler: func(s *Switch) http.Handler {
+			called = s
+			return http.HandlerFunc(func(response http.ResponseWriter, _ *http.Request) {
+				response.WriteHeader(http.StatusAccepted)
+			})
+		},
+	})
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest("PUT", "/switches/"+name+"/postpone", nil))
+	if expected, ok := r.Get(name); ok {
+		suite.Same(expected, called)
+	} else {
+		suite.Nil(called)
+	}
+
+	return response.Result()
+}
+
+func (suite *SwitchHandlerSuite) TestServeHTTP() {
+	r := NewRegistry()
+	suite.Require().NoError(r.Register("test", suite.newSwitch(SwitchConfig{Logger: suite.logger})))
+
+	suite.Equal(http.StatusAccepted, suite.serve(r, "test").StatusCode)
+	suite.Equal(http.StatusNotFound, suite.serve(r, "nosuch").StatusCode)
+}
+
+func TestSwitchHandler(t *testing.T) {
+	suite.Run(t, new(SwitchHandlerSuite))
+}
+
 type ProvideHTTPSuite struct {
 	DMSSuite
 }
@@ -226,6 +853,362 @@ func (suite *ProvideHTTPSuite) TestPostponed() {
 	})
 }
 
+func (suite *ProvideHTTPSuite) TestNamedSwitch() {
+	var (
+		mockActions = newMockActions(1)
+		cfg, _      = suite.switchConfig(time.Hour, 0, mockActions.actions()...)
+		named       = suite.newSwitch(cfg)
+		registry    = NewRegistry()
+		p           = new(mockPostponer)
+		s           *http.Server
+	)
+
+	suite.Require().NoError(registry.Register("test", named))
+	app := fxtest.New(
+		suite.T(),
+		fx.Logger(DiscardLogger{}),
+		suite.provideLogger(),
+		fx.Supply(CommandLine{}, registry),
+		provideHTTP(),
+		fx.Provide(
+			func() Postponer { return p },
+		),
+		fx.Populate(&s),
+	)
+
+	app.RequireStart()
+	suite.Require().NotNil(s)
+
+	put := func(path string) int {
+		request, err := http.NewRequest("PUT", fmt.Sprintf("http://%s%s", s.Addr, path), nil)
+		suite.Require().NoError(err)
+
+		response, err := http.DefaultClient.Do(request)
+		suite.Require().NoError(err)
+		io.Copy(io.Discard, response.Body)
+		response.Body.Close()
+		return response.StatusCode
+	}
+
+	// the switch isn't active yet
+	suite.Equal(http.StatusServiceUnavailable, put("/switches/test/postpone"))
+	suite.Equal(http.StatusNotFound, put("/switches/nosuch/postpone"))
+
+	go named.Activate()
+	suite.Eventually(
+		func() bool { return put("/switches/test/postpone") == http.StatusOK },
+		time.Second,
+		10*time.Millisecond,
+	)
+
+	suite.NoError(named.Deactivate())
+
+	response, err := http.Get(fmt.Sprintf("http://%s/switches/test/status", s.Addr))
+	suite.Require().NoError(err)
+	defer response.Body.Close()
+	suite.Equal(http.StatusOK, response.StatusCode)
+
+	var st Status
+	suite.Require().NoError(json.NewDecoder(response.Body).Decode(&st))
+	suite.Equal(StateDeactivated, st.State)
+
+	app.RequireStop()
+	p.AssertExpectations(suite.T())
+	mockActions.assertExpectations(suite.T())
+}
+
+func (suite *ProvideHTTPSuite) TestEvents() {
+	var (
+		p      = new(mockPostponer)
+		events = NewEvents(0)
+		s      *http.Server
+	)
+
+	app := fxtest.New(
+		suite.T(),
+		fx.Logger(DiscardLogger{}),
+		suite.provideLogger(),
+		fx.Supply(CommandLine{}, events),
+		provideHTTP(),
+		fx.Provide(
+			func() Postponer { return p },
+		),
+		fx.Populate(&s),
+	)
+
+	app.RequireStart()
+	suite.Require().NotNil(s)
+
+	response, err := http.Get(fmt.Sprintf("http://%s%s", s.Addr, EventsPath))
+	suite.Require().NoError(err)
+	defer response.Body.Close()
+	suite.Equal(http.StatusOK, response.StatusCode)
+
+	// an open event stream must not hold up shutdown
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		app.RequireStop()
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		suite.Fail("an open event stream blocked shutdown")
+	}
+
+	p.AssertExpectations(suite.T())
+}
+
+func (suite *ProvideHTTPSuite) TestTrigger() {
+	var (
+		p = new(mockPostponer)
+		t = new(mockTriggerer)
+		s *http.Server
+	)
+
+	newApp := func(cl CommandLine) *fxtest.App {
+		return fxtest.New(
+			suite.T(),
+			fx.Logger(DiscardLogger{}),
+			suite.provideLogger(),
+			fx.Supply(cl),
+			provideHTTP(),
+			fx.Provide(
+				func() Postponer { return p },
+				func() Triggerer { return t },
+			),
+			fx.Populate(&s),
+		)
+	}
+
+	post := func(token string, form url.Values) *http.Response {
+		request, err := http.NewRequest("POST", fmt.Sprintf("http://%s%s", s.Addr, TriggerPath), strings.NewReader(form.Encode()))
+		suite.Require().NoError(err)
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if len(token) > 0 {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		response, err := http.DefaultClient.Do(request)
+		suite.Require().NoError(err)
+		return response
+	}
+
+	suite.Run("NoAdminToken", func() {
+		app := newApp(CommandLine{})
+		app.RequireStart()
+		response := post("secret", url.Values{ReasonParameter: {"test"}})
+		response.Body.Close()
+		suite.Equal(http.StatusNotFound, response.StatusCode)
+		app.RequireStop()
+	})
+
+	suite.Run("AdminToken", func() {
+		app := newApp(CommandLine{AdminToken: []string{"ops=secret"}})
+		app.RequireStart()
+
+		response := post("wrong", url.Values{ReasonParameter: {"test"}})
+		response.Body.Close()
+		suite.Equal(http.StatusUnauthorized, response.StatusCode)
+
+		response = post("secret", url.Values{ReasonParameter: {"test"}})
+		suite.Equal(http.StatusAccepted, response.StatusCode)
+		var tc TriggerConfirmation
+		suite.Require().NoError(json.NewDecoder(response.Body).Decode(&tc))
+		response.Body.Close()
+
+		t.ExpectTrigger(mock.MatchedBy(func(tr TriggerRequest) bool {
+			return tr.Source == "ops" && tr.Reason == "test" && len(tr.RemoteAddr) > 0
+		})).Return(nil).Once()
+
+		response = post("secret", url.Values{ConfirmParameter: {tc.Confirm}})
+		response.Body.Close()
+		suite.Equal(http.StatusOK, response.StatusCode)
+		app.RequireStop()
+	})
+
+	suite.Run("InvalidAdminToken", func() {
+		app := fx.New(
+			fx.Logger(DiscardLogger{}),
+			suite.provideLogger(),
+			fx.Supply(CommandLine{AdminToken: []string{"secret"}}),
+			provideHTTP(),
+			fx.Provide(
+				func() Postponer { return p },
+			),
+		)
+
+		suite.ErrorIs(app.Err(), ErrInvalidAdminToken)
+	})
+
+	p.AssertExpectations(suite.T())
+	t.AssertExpectations(suite.T())
+}
+
+func (suite *ProvideHTTPSuite) TestDeactivate() {
+	var (
+		mockActions = newMockActions(1)
+		cfg, _      = suite.switchConfig(time.Hour, 0, mockActions.actions()...)
+		named       = suite.newSwitch(cfg)
+		registry    = NewRegistry()
+		p           = new(mockPostponer)
+		d           = new(mockDeactivator)
+		s           *http.Server
+	)
+
+	suite.Require().NoError(registry.Register("test", named))
+	app := fxtest.New(
+		suite.T(),
+		fx.Logger(DiscardLogger{}),
+		suite.provideLogger(),
+		fx.Supply(CommandLine{AdminToken: []string{"ops=secret"}}, registry),
+		provideHTTP(),
+		fx.Provide(
+			func() Postponer { return p },
+			func() Deactivator { return d },
+		),
+		fx.Populate(&s),
+	)
+
+	app.RequireStart()
+
+	// don't leave idle connections behind, which would delay the server's shutdown
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	post := func(path, token string) (*http.Response, Status) {
+		request, err := http.NewRequest("POST", fmt.Sprintf("http://%s%s", s.Addr, path), nil)
+		suite.Require().NoError(err)
+		request.Header.Set("Authorization", "Bearer "+token)
+
+		response, err := client.Do(request)
+		suite.Require().NoError(err)
+		defer response.Body.Close()
+
+		var st Status
+		if response.StatusCode == http.StatusOK {
+			suite.Require().NoError(json.NewDecoder(response.Body).Decode(&st))
+		}
+
+		return response, st
+	}
+
+	go named.Activate()
+	suite.Eventually(
+		func() bool { return named.Status().State == StateActive },
+		time.Second,
+		10*time.Millisecond,
+	)
+
+	response, _ := post("/switches/test/deactivate", "wrong")
+	suite.Equal(http.StatusUnauthorized, response.StatusCode)
+
+	response, st := post("/switches/test/deactivate", "secret")
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.Equal(StateDeactivated, st.State)
+
+	response, _ = post("/switches/test/deactivate", "secret")
+	suite.Equal(http.StatusServiceUnavailable, response.StatusCode)
+
+	d.ExpectStandDown(mock.MatchedBy(func(dr DeactivateRequest) bool {
+		return dr.Source == "ops" && len(dr.RemoteAddr) > 0
+	})).Return(Status{State: StateDeactivated}, nil).Once()
+
+	response, st = post(DeactivatePath, "secret")
+	suite.Equal(http.StatusOK, response.StatusCode)
+	suite.Equal(StateDeactivated, st.State)
+
+	app.RequireStop()
+	p.AssertExpectations(suite.T())
+	d.AssertExpectations(suite.T())
+	mockActions.assertExpectations(suite.T())
+}
+
+func (suite *ProvideHTTPSuite) TestGuarded() {
+	var (
+		cfg, _   = suite.switchConfig(time.Hour, 0)
+		named    = suite.newSwitch(cfg)
+		registry = NewRegistry()
+		p        = new(mockPostponer)
+		pauser   = new(mockPauser)
+		rearmer  = new(mockRearmer)
+		aborter  = new(mockAborter)
+		s        *http.Server
+	)
+
+	suite.Require().NoError(registry.Register("test", named))
+	app := fxtest.New(
+		suite.T(),
+		fx.Logger(DiscardLogger{}),
+		suite.provideLogger(),
+		fx.Supply(CommandLine{AdminToken: []string{"ops=secret"}}, registry),
+		provideHTTP(),
+		fx.Provide(
+			func() Postponer { return p },
+			func() Pauser { return pauser },
+			func() Rearmer { return rearmer },
+			func() Aborter { return aborter },
+		),
+		fx.Populate(&s),
+	)
+
+	app.RequireStart()
+
+	// don't leave idle connections behind, which would delay the server's shutdown
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	put := func(path, token string) int {
+		request, err := http.NewRequest("PUT", fmt.Sprintf("http://%s%s", s.Addr, path), nil)
+		suite.Require().NoError(err)
+		if len(token) > 0 {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		response, err := client.Do(request)
+		suite.Require().NoError(err)
+		response.Body.Close()
+		return response.StatusCode
+	}
+
+	for _, path := range []string{
+		PausePath + "?reason=deploy&duration=15m",
+		ResumePath,
+		RearmPath,
+		AbortPath,
+		"/switches/test/pause?reason=deploy&duration=15m",
+		"/switches/test/resume",
+		"/switches/test/rearm",
+		"/switches/test/abort",
+	} {
+		suite.Run(path, func() {
+			suite.Equal(http.StatusUnauthorized, put(path, ""))
+			suite.Equal(http.StatusUnauthorized, put(path, "wrong"))
+		})
+	}
+
+	pauser.ExpectPause(mock.MatchedBy(func(pr PauseRequest) bool {
+		return pr.Reason == "deploy" && pr.Duration == 15*time.Minute
+	})).Return(nil).Once()
+	suite.Equal(http.StatusOK, put(PausePath+"?reason=deploy&duration=15m", "secret"))
+
+	pauser.ExpectResume().Return(nil).Once()
+	suite.Equal(http.StatusOK, put(ResumePath, "secret"))
+
+	rearmer.ExpectRearm().Return(nil).Once()
+	suite.Equal(http.StatusOK, put(RearmPath, "secret"))
+
+	aborter.ExpectAbort().Return(true).Once()
+	suite.Equal(http.StatusOK, put(AbortPath, "secret"))
+
+	// the named switch is not active
+	suite.Equal(http.StatusServiceUnavailable, put("/switches/test/rearm", "secret"))
+	suite.Equal(http.StatusConflict, put("/switches/test/abort", "secret"))
+
+	app.RequireStop()
+	p.AssertExpectations(suite.T())
+	pauser.AssertExpectations(suite.T())
+	rearmer.AssertExpectations(suite.T())
+	aborter.AssertExpectations(suite.T())
+}
+
 func (suite *ProvideHTTPSuite) TestNotFound() {
 	var (
 		p   = new(mockPostponer)
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"time"
+)
+
+// SwitchListener receives notifications of a switch's lifecycle events.  Each
+// callback is passed the name of the switch, which is empty for the default switch,
+// and the time of the event according to the switch's clock.
+//
+// Callbacks are invoked synchronously from the switch's loop, so implementations
+// must be safe for concurrent use and must not block.
+type SwitchListener interface {
+	// OnPostpone is called for each postpone the switch accepts.
+	OnPostpone(name string, t time.Time, pr PostponeRequest)
+
+	// OnMiss is called each time the switch counts a miss.
+	OnMiss(name string, t time.Time, misses, maxMisses int)
+
+	// OnTrigger is called when the switch triggers, before its actions run.
+	OnTrigger(name string, t time.Time, misses int, d Details)
+
+	// OnDeactivate is called when the switch is deactivated.
+	OnDeactivate(name string, t time.Time)
+
+	// OnActionResult is called after each of the switch's actions runs, including
+	// escalation and recovery actions.  The err is the action's result.
+	OnActionResult(name string, t time.Time, a Action, err error)
+}
+
+// NopSwitchListener is a SwitchListener that ignores all events.  It can be
+// embedded to implement only some of the SwitchListener callbacks.
+type NopSwitchListener struct{}
+
+var _ SwitchListener = NopSwitchListener{}
+
+func (NopSwitchListener) OnPostpone(string, time.Time, PostponeRequest)   {}
+func (NopSwitchListener) OnMiss(string, time.Time, int, int)              {}
+func (NopSwitchListener) OnTrigger(string, time.Time, int, Details)       {}
+func (NopSwitchListener) OnDeactivate(string, time.Time)                  {}
+func (NopSwitchListener) OnActionResult(string, time.Time, Action, error) {}
+
+// SwitchListeners is an aggregate SwitchListener that dispatches each event
+// to its listeners, in order.
+type SwitchListeners []SwitchListener
+
+var _ SwitchListener = SwitchListeners(nil)
+
+func (sl SwitchListeners) OnPostpone(name string, t time.Time, pr PostponeRequest) {
+	for _, l := range sl {
+		l.OnPostpone(name, t, pr)
+	}
+}
+
+func (sl SwitchListeners) OnMiss(name string, t time.Time, misses, maxMisses int) {
+	for _, l := range sl {
+		l.OnMiss(name, t, misses, maxMisses)
+	}
+}
+
+func (sl SwitchListeners) OnTrigger(name string, t time.Time, misses int, d Details) {
+	for _, l := range sl {
+		l.OnTrigger(name, t, misses, d)
+	}
+}
+
+func (sl SwitchListeners) OnDeactivate(name string, t time.Time) {
+	for _, l := range sl {
+		l.OnDeactivate(name, t)
+	}
+}
+
+func (sl SwitchListeners) OnActionResult(name string, t time.Time, a Action, err error) {
+	for _, l := range sl {
+		l.OnActionResult(name, t, a, err)
+	}
+}
@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/xmidt-org/chronon"
+)
+
+type SwitchListenerSuite struct {
+	DMSSuite
+}
+
+func (suite *SwitchListenerSuite) TestNop() {
+	var l SwitchListener = NopSwitchListener{}
+	suite.NotPanics(func() {
+		l.OnPostpone("", suite.now, PostponeRequest{})
+		l.OnMiss("", suite.now, 1, 2)
+		l.OnTrigger("", suite.now, 2, nil)
+		l.OnDeactivate("", suite.now)
+		l.OnActionResult("", suite.now, nil, nil)
+	})
+}
+
+func (suite *SwitchListenerSuite) TestSwitchListeners() {
+	var (
+		first  = new(mockSwitchListener)
+		second = new(mockSwitchListener)
+		sl     = SwitchListeners{first, second}
+
+		pr     = PostponeRequest{Source: "test"}
+		d      = Details{MissesDetail: "2"}
+		action = newMockActions(1)[0]
+		err    = errors.New("expected")
+	)
+
+	for _, m := range []*mockSwitchListener{first, second} {
+		m.ExpectOnPostpone("test", suite.now, pr).Once()
+		m.ExpectOnMiss("test", suite.now, 1, 2).Once()
+		m.ExpectOnTrigger("test", suite.now, 2, d).Once()
+		m.ExpectOnDeactivate("test", suite.now).Once()
+		m.ExpectOnActionResult("test", suite.now, action, err).Once()
+	}
+
+	sl.OnPostpone("test", suite.now, pr)
+	sl.OnMiss("test", suite.now, 1, 2)
+	sl.OnTrigger("test", suite.now, 2, d)
+	sl.OnDeactivate("test", suite.now)
+	sl.OnActionResult("test", suite.now, action, err)
+
+	first.AssertExpectations(suite.T())
+	second.AssertExpectations(suite.T())
+}
+
+func (suite *SwitchListenerSuite) TestSwitch() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(2)
+			actionErr   = errors.New("expected")
+			listener    = new(mockSwitchListener)
+			cfg, clock  = suite.switchConfig(ttl, 2, mockActions.actions()...)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+			pr          = PostponeRequest{Source: "test"}
+		)
+
+		cfg.Name = "named"
+		cfg.Listeners = []SwitchListener{listener}
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+		listener.ExpectOnPostpone("named", clock.Now(), pr).Once()
+		suite.True(s.Postpone(pr))
+		synctest.Wait()
+
+		listener.ExpectOnMiss("named", clock.Now().Add(ttl), 1, 2).Once()
+		clock.Add(ttl)
+		synctest.Wait()
+
+		triggeredAt := clock.Now().Add(ttl)
+		listener.ExpectOnMiss("named", triggeredAt, 2, 2).Once()
+		listener.ExpectOnTrigger("named", triggeredAt, 2, mock.Anything).Once()
+		listener.ExpectOnActionResult("named", triggeredAt, mockActions[0], actionErr).Once()
+		listener.ExpectOnActionResult("named", triggeredAt, mockActions[1], nil).Once()
+		mockActions[0].ExpectRun().Return(actionErr).Once()
+		mockActions[1].ExpectRun().Return(nil).Once()
+
+		clock.Add(ttl)
+		suite.NoError(<-done)
+		mockActions.assertExpectations(suite.T())
+		listener.AssertExpectations(suite.T())
+	})
+}
+
+func (suite *SwitchListenerSuite) TestDeactivate() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			listener   = new(mockSwitchListener)
+			cfg, clock = suite.switchConfig(10*time.Second, 1)
+			done       = make(chan error)
+			onTicker   = make(chan chronon.FakeTicker, 1)
+		)
+
+		cfg.Listeners = []SwitchListener{listener}
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+		listener.ExpectOnDeactivate("", clock.Now()).Once()
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+		listener.AssertExpectations(suite.T())
+	})
+}
+
+func TestSwitchListener(t *testing.T) {
+	suite.Run(t, new(SwitchListenerSuite))
+}
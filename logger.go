@@ -55,3 +55,16 @@ func logServerError(l Logger, err error) {
 		l.Printf("HTTP server error: %s", err)
 	}
 }
+
+// PrefixLogger is a Logger that prepends a fixed prefix to each line of output.
+// This is used to distinguish the output of named switches.
+type PrefixLogger struct {
+	Prefix string
+	Logger Logger
+}
+
+// Printf logs the prefix as is, so that a prefix containing % does not alter
+// the formatting of the message.
+func (pl PrefixLogger) Printf(format string, args ...interface{}) {
+	pl.Logger.Printf("%s"+format, append([]interface{}{pl.Prefix}, args...)...)
+}
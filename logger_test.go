@@ -61,6 +61,22 @@ func (suite *LoggerSuite) TestWriterLoggerError() {
 	})
 }
 
+func (suite *LoggerSuite) TestPrefixLogger() {
+	pl := PrefixLogger{
+		Prefix: "[switch=test] ",
+		Logger: WriterLogger{Writer: suite.capture},
+	}
+
+	pl.Printf("test: %d", 123)
+	suite.Equal("[switch=test] test: 123\n", suite.capture.String())
+
+	// the prefix is not a format
+	suite.capture.Reset()
+	pl.Prefix = "[switch=50%d] "
+	pl.Printf("test: %s", "x")
+	suite.Equal("[switch=50%d] test: x\n", suite.capture.String())
+}
+
 func (suite *LoggerSuite) TestDiscardLogger() {
 	DiscardLogger{}.Printf("test: %d", 123)
 }
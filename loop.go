@@ -0,0 +1,716 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/xmidt-org/chronon"
+)
+
+// command is a request executed by the Activate loop.  Commands have
+// exclusive access to the loop's state.
+type command func(*loop)
+
+// loop holds the state of a single invocation of Switch.Activate.  A loop
+// is only ever accessed from the Activate goroutine.
+type loop struct {
+	s *Switch
+	m monitor
+	p policy
+	t chronon.Ticker
+
+	// now is the time of the event currently being handled.
+	now time.Time
+
+	// armed is false until any grace period or required postpones have
+	// been satisfied.  graceEnd is the zero time if there is no grace period.
+	// postpones counts the consecutive postpones received while unarmed.
+	armed         bool
+	graceEnd      time.Time
+	postpones     int
+	lastPostponed time.Time
+	lastRequest   PostponeRequest
+
+	// held is true while a trigger marker exists.  A held loop is unarmed,
+	// and does not count postpones toward arming.
+	held bool
+
+	// tripped is true when a re-arming switch has triggered and is waiting
+	// to be re-armed.
+	tripped   bool
+	trippedAt time.Time
+
+	// paused is true while a pause is in effect, which lasts until pauseEnd.
+	paused   bool
+	pauseEnd time.Time
+
+	// windowEnd is the time the currently open maintenance window closes, or
+	// the zero time if no maintenance window is open.
+	windowEnd time.Time
+
+	// triggers holds the times at which actions ran within the trigger window.
+	triggers []time.Time
+
+	// fired tracks which of the switch's escalation tiers have fired.
+	fired []bool
+
+	// saved is the state most recently written to the switch's state file.
+	saved State
+
+	// outageStart is the time the current outage was first detected, or the
+	// zero time if there is no outage.  outageMisses is the largest number of
+	// consecutive misses seen during the outage.
+	outageStart  time.Time
+	outageMisses int
+
+	// done is set by a command or postpone that terminated the switch, in which case
+	// err is the result for Activate.
+	done bool
+	err  error
+}
+
+func newLoop(s *Switch, m monitor) *loop {
+	l := &loop{
+		s:     s,
+		m:     m,
+		p:     s.newPolicy(),
+		now:   s.clock.Now(),
+		armed: s.grace <= 0 && s.armAfter <= 0,
+		fired: make([]bool, len(s.tiers)),
+	}
+
+	switch {
+	case l.marked():
+		l.armed = false
+		l.held = true
+
+	case l.restore():
+		// picking up where a previous activation left off
+
+	case l.armed:
+		l.p.start(l.now)
+
+	case s.grace > 0:
+		l.graceEnd = l.now.Add(s.grace)
+		s.logger.Printf("unarmed [grace=%s] [armAfter=%d]", s.grace, s.armAfter)
+
+	default:
+		s.logger.Printf("unarmed [armAfter=%d]", s.armAfter)
+	}
+
+	d, ok := l.next()
+	if !ok {
+		d = s.ttl
+	}
+
+	l.publish()
+	l.t = s.clock.NewTicker(d)
+	if !ok {
+		l.t.Stop()
+	}
+
+	return l
+}
+
+// run handles events until this loop's switch is deactivated or terminated.
+func (l *loop) run() error {
+	for {
+		select {
+		case pr := <-l.m.postpone:
+			l.now = l.s.clock.Now()
+			l.postpone(pr)
+			if l.done {
+				return l.err
+			}
+
+		case c := <-l.m.commands:
+			l.now = l.s.clock.Now()
+			c(l)
+			if l.done {
+				return l.err
+			}
+
+		case <-l.m.deactivate:
+			l.s.finish(l.status(StateDeactivated))
+			l.s.listener.OnDeactivate(l.s.name, l.s.clock.Now())
+			l.s.logger.Printf("deactivated")
+			return ErrDeactivated
+
+		case <-l.t.C():
+			// use the clock's current time rather than the tick, so that any
+			// ticks dropped while this loop was busy are accounted for
+			l.now = l.s.clock.Now()
+			if !l.armed {
+				if !l.graceEnd.IsZero() && !l.now.Before(l.graceEnd) {
+					l.arm()
+				}
+
+				break
+			}
+
+			if l.paused {
+				if !l.now.Before(l.pauseEnd) {
+					l.s.logger.Printf("pause expired")
+					l.resume()
+				}
+
+				break
+			}
+
+			if l.tripped || l.maintenance() {
+				break
+			}
+
+			misses := l.p.misses()
+			v := l.p.expire(l.now)
+			if v.misses > misses {
+				l.s.listener.OnMiss(l.s.name, l.now, v.misses, l.s.maxMisses)
+			}
+
+			l.outage(v.misses)
+			l.escalate(v.misses)
+			if v.trigger {
+				if done, err := l.trigger(v.details); done {
+					return err
+				}
+			}
+		}
+
+		l.settle()
+	}
+}
+
+// settle brings everything that depends on this loop's state up to date after
+// an event: the ticker, the state file, and the published status.  Settling
+// again without any change to the state has no effect.
+func (l *loop) settle() {
+	l.schedule()
+	l.persist()
+	l.publish()
+}
+
+// schedule resets the ticker for the loop's next event, or stops the
+// ticker if there is no next event.
+func (l *loop) schedule() {
+	if d, ok := l.next(); ok {
+		l.t.Reset(d)
+	} else {
+		l.t.Stop()
+	}
+}
+
+// next returns the interval until the loop should next tick.  A tripped loop
+// has no deadline, so this method returns false.  An unarmed loop only ticks
+// at the end of any grace period, and a paused loop only ticks at the end of
+// the pause.  An open maintenance window only ticks when it closes, and
+// otherwise the loop also ticks when the next maintenance window opens.
+func (l *loop) next() (d time.Duration, ok bool) {
+	switch {
+	case l.paused:
+		d = l.pauseEnd.Sub(l.now)
+
+	case l.tripped, !l.armed && l.graceEnd.IsZero():
+		return 0, false
+
+	case !l.armed:
+		d = l.graceEnd.Sub(l.now)
+
+	case !l.windowEnd.IsZero():
+		d = l.windowEnd.Sub(l.now)
+
+	default:
+		deadline := l.p.deadline()
+		if next := l.nextWindow(); !next.IsZero() && next.Before(deadline) {
+			deadline = next
+		}
+
+		d = deadline.Sub(l.now)
+	}
+
+	if d <= 0 {
+		// the deadline has already passed, so tick as soon as possible
+		d = time.Nanosecond
+	}
+
+	return d, true
+}
+
+// postpone handles a postpone request.  If tripped, the postpone re-arms
+// the switch once the cooldown has elapsed.  A postpone whose heartbeat fails
+// the switch's rules is handled by unhealthy instead.
+func (l *loop) postpone(pr PostponeRequest) {
+	if failed := l.s.rules.Failed(pr.Heartbeat); len(failed) > 0 {
+		l.unhealthy(pr, failed)
+		return
+	}
+
+	switch {
+	case l.held && l.marked():
+		l.s.logger.Printf("postponed %s while held by a trigger marker", pr)
+
+	case l.held:
+		l.held = false
+		l.s.logger.Printf("postponed %s after the trigger marker was cleared", pr)
+		l.arm()
+
+	case !l.armed:
+		if l.postpones > 0 && l.now.Sub(l.lastPostponed) >= l.s.ttl {
+			// not consecutive, so start counting again
+			l.postpones = 0
+		}
+
+		l.postpones++
+		l.s.logger.Printf("postponed %s while unarmed [postpones=%d]", pr, l.postpones)
+		if l.s.armAfter > 0 && l.postpones >= l.s.armAfter {
+			l.arm()
+		}
+
+	case l.paused:
+		l.s.logger.Printf("postponed %s while paused", pr)
+
+	case !l.tripped && !l.windowEnd.IsZero():
+		l.s.logger.Printf("postponed %s during maintenance window", pr)
+
+	case !l.tripped:
+		l.p.postpone(l.now, pr)
+		l.s.logger.Printf("postponed %s", pr)
+		l.escalate(l.p.misses())
+		if l.p.misses() == 0 {
+			l.recover()
+		}
+
+	case l.now.Before(l.trippedAt.Add(l.s.cooldown)):
+		l.s.logger.Printf("postponed %s during cooldown, remaining tripped", pr)
+
+	default:
+		l.s.logger.Printf("postponed %s", pr)
+		l.rearm()
+		l.recover()
+	}
+
+	l.lastPostponed = l.now
+	l.lastRequest = pr
+	l.s.listener.OnPostpone(l.s.name, l.now, pr)
+}
+
+// unhealthy handles a postpone whose heartbeat failed the given rules.  Such a
+// postpone never postpones the switch, so by default its deadline passes and
+// counts as a miss.  A switch that triggers on unhealthy heartbeats does so only
+// when it would otherwise be counting misses.
+func (l *loop) unhealthy(pr PostponeRequest, failed Rules) {
+	l.s.logger.Printf("unhealthy heartbeat %s [failed=%s]", pr, failed)
+	if !l.s.triggerUnhealthy || !l.armed || l.tripped || l.paused || !l.windowEnd.IsZero() {
+		return
+	}
+
+	d := Details{UnhealthyDetail: failed.String()}
+	if len(pr.Source) > 0 {
+		d[SourceDetail] = pr.Source
+	}
+
+	l.done, l.err = l.trigger(d)
+}
+
+// pause suspends counting misses until the pause ends.
+func (l *loop) pause(pr PauseRequest) error {
+	if !l.armed || l.tripped {
+		return ErrNotArmed
+	}
+
+	l.paused = true
+	l.pauseEnd = l.now.Add(pr.Duration)
+	l.s.logger.Printf("paused %s", pr)
+	return nil
+}
+
+// resume ends a pause, restarting the policy.
+func (l *loop) resume() error {
+	if !l.paused {
+		return ErrNotPaused
+	}
+
+	l.paused = false
+	l.p.start(l.now)
+	l.escalate(0)
+	l.s.logger.Printf("resumed")
+	return nil
+}
+
+// maintenance opens and closes this loop's maintenance windows, returning true
+// if a window is open.  When a window closes, the policy restarts.
+func (l *loop) maintenance() bool {
+	var (
+		end  time.Time
+		open bool
+	)
+
+	for _, w := range l.s.windows {
+		if e, o := w.End(l.now); o && e.After(end) {
+			end, open = e, true
+		}
+	}
+
+	switch {
+	case open:
+		if l.windowEnd.IsZero() {
+			l.s.logger.Printf("maintenance window opened [until=%s]", end)
+		}
+
+		l.windowEnd = end
+		return true
+
+	case !l.windowEnd.IsZero():
+		l.windowEnd = time.Time{}
+		l.p.start(l.now)
+		l.escalate(0)
+		l.s.logger.Printf("maintenance window closed")
+	}
+
+	return false
+}
+
+// nextWindow returns the next time any maintenance window opens, or the
+// zero time if there are no maintenance windows.
+func (l *loop) nextWindow() (next time.Time) {
+	for _, w := range l.s.windows {
+		if n := w.Next(l.now); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+
+	return
+}
+
+// arm starts counting misses for a switch that was held unarmed.
+func (l *loop) arm() {
+	l.armed = true
+	l.p.start(l.now)
+	l.s.logger.Printf("armed")
+}
+
+// outage records the start of an outage, if the given misses begin one.
+func (l *loop) outage(misses int) {
+	if misses > 0 && l.outageStart.IsZero() {
+		l.outageStart = l.now
+	}
+
+	if misses > l.outageMisses {
+		l.outageMisses = misses
+	}
+}
+
+// recover ends the current outage, if any, and runs the switch's recover actions.
+func (l *loop) recover() {
+	if l.outageStart.IsZero() {
+		return
+	}
+
+	d := Details{
+		MissesDetail: strconv.Itoa(l.outageMisses),
+		OutageDetail: l.now.Sub(l.outageStart).String(),
+	}
+
+	l.outageStart = time.Time{}
+	l.outageMisses = 0
+
+	l.s.logger.Printf("recovered %s", d)
+	l.runActions(d, nil, l.s.recover)
+}
+
+// rearm re-arms a tripped switch, restarting its policy and removing its
+// trigger marker.
+func (l *loop) rearm() error {
+	if !l.tripped {
+		return ErrNotTripped
+	}
+
+	l.restart()
+	l.unmark()
+	l.s.logger.Printf("rearmed")
+	return nil
+}
+
+// restart untrips the switch and restarts its policy, so that it counts toward
+// its next trigger.
+func (l *loop) restart() {
+	l.tripped = false
+	l.p.start(l.now)
+	l.escalate(0)
+}
+
+// triggerNow triggers the switch on request, regardless of its misses.  The
+// trigger is deliberate, so any grace period, pause, or maintenance window
+// is ignored.
+func (l *loop) triggerNow(tr TriggerRequest) error {
+	switch {
+	case l.held:
+		return ErrHeld
+
+	case l.tripped:
+		return ErrTripped
+
+	case !l.armed:
+		l.arm()
+	}
+
+	l.paused = false
+	l.s.logger.Printf("manually triggered %s", tr)
+	l.done, l.err = l.trigger(tr.details())
+	return nil
+}
+
+// trigger runs the switch's actions.  If the switch does not re-arm, this
+// terminates the switch and returns true along with the result for Activate.
+// Otherwise, the switch trips and this method returns false.  A dry run that
+// does not re-arm trips and immediately re-arms, so that it keeps counting.
+//
+// A switch whose actions were aborted by the FailureAbortAndKeepRunning policy
+// also keeps counting, but keeps its trigger marker, since some of its actions
+// may have run.
+func (l *loop) trigger(d Details) (done bool, err error) {
+	if !l.s.rearm && !l.s.dryRun {
+		// record the trigger before running any actions, in case an action
+		// causes this process to exit
+		l.mark(d)
+		l.save(State{
+			LastPostpone: l.lastPostponed,
+			Misses:       l.p.misses(),
+			Triggered:    true,
+			TriggeredAt:  l.now,
+		})
+
+		l.s.finish(l.status(StateTriggered))
+		l.s.listener.OnTrigger(l.s.name, l.now, l.p.misses(), d)
+		exit, aborted := l.s.terminate(l.m.ctx, d, l.s.after, l.m.actions...)
+		switch {
+		case aborted:
+			// dms keeps running, so the switch must keep counting rather than stop,
+			// and must no longer be recorded as triggered
+			l.s.logger.Printf("tripped")
+			l.s.logger.Printf("actions aborted, restarting rather than stopping")
+			l.restart()
+			l.persist()
+			return false, nil
+
+		case exit == nil:
+			// the switch was deactivated before it could trigger
+			l.s.finish(l.status(StateDeactivated))
+			l.s.listener.OnDeactivate(l.s.name, l.now)
+			err = ErrDeactivated
+		}
+
+		return true, err
+	}
+
+	l.tripped = true
+	l.trippedAt = l.now
+	if l.allowTrigger() {
+		l.mark(d)
+		l.s.listener.OnTrigger(l.s.name, l.now, l.p.misses(), d)
+		l.runActions(d, l.s.after, l.m.actions)
+	}
+
+	l.s.logger.Printf("tripped")
+	if l.s.dryRun && !l.s.rearm {
+		// a real switch would have stopped, but a dry run keeps counting so
+		// that later deadlines can be observed
+		l.s.logger.Printf("dry run: re-arming rather than stopping")
+		l.rearm()
+	}
+
+	return false, nil
+}
+
+// marked tests if the switch's trigger marker exists.
+func (l *loop) marked() bool {
+	if len(l.s.markerFile) == 0 {
+		return false
+	}
+
+	m, err := ReadMarker(l.s.markerFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return false
+
+	case err != nil:
+		// err on the side of not triggering again
+		l.s.logger.Printf("held by an unreadable trigger marker %s: %s", l.s.markerFile, err)
+
+	case !l.held:
+		l.s.logger.Printf("held by trigger marker %s %s", l.s.markerFile, m)
+	}
+
+	return true
+}
+
+// mark writes the switch's trigger marker, if it has one.  A failure to write
+// the marker is logged, but does not prevent the switch from triggering.
+func (l *loop) mark(d Details) {
+	if len(l.s.markerFile) == 0 {
+		return
+	}
+
+	if err := WriteMarker(l.s.markerFile, Marker{TriggeredAt: l.now, Misses: l.p.misses(), Details: d}); err != nil {
+		l.s.logger.Printf("unable to write trigger marker: %s", err)
+	}
+}
+
+// unmark removes the switch's trigger marker, since a re-armed switch may trigger again.
+func (l *loop) unmark() {
+	if len(l.s.markerFile) == 0 {
+		return
+	}
+
+	if err := os.Remove(l.s.markerFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		l.s.logger.Printf("unable to remove trigger marker: %s", err)
+	}
+}
+
+// restore picks up from the switch's state file, if any.  This method returns
+// true if state was restored.
+func (l *loop) restore() bool {
+	if len(l.s.stateFile) == 0 {
+		return false
+	}
+
+	st, err := ReadState(l.s.stateFile)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return false
+
+	case err != nil:
+		l.s.logger.Printf("unable to read state file: %s", err)
+		return false
+
+	case st.Triggered && !l.s.rearm:
+		// the previous activation triggered and exited, so start over
+		l.s.logger.Printf("previously triggered %s", st)
+		return false
+
+	case !st.Triggered && st.Deadline.IsZero():
+		return false
+	}
+
+	l.armed = true
+	l.lastPostponed = st.LastPostpone
+	l.saved = st
+	if st.Triggered {
+		l.tripped = true
+		l.trippedAt = st.TriggeredAt
+	} else {
+		l.p.restore(st.Deadline, st.Misses)
+		l.outage(st.Misses)
+
+		// don't run tiers again that ran before the restart
+		for i, tier := range l.s.tiers {
+			l.fired[i] = st.Misses >= tier.Misses
+		}
+	}
+
+	l.s.logger.Printf("restored state %s", st)
+	return true
+}
+
+// persist writes this loop's current state to the switch's state file.
+// Unarmed loops have no countdown to persist.
+func (l *loop) persist() {
+	if !l.armed {
+		return
+	}
+
+	st := State{
+		LastPostpone: l.lastPostponed,
+		Misses:       l.p.misses(),
+		Triggered:    l.tripped,
+	}
+
+	if l.tripped {
+		st.TriggeredAt = l.trippedAt
+	} else {
+		st.Deadline = l.p.deadline()
+	}
+
+	l.save(st)
+}
+
+// save atomically writes the given state to the switch's state file, if
+// the switch has one and the state has changed.
+func (l *loop) save(st State) {
+	if len(l.s.stateFile) == 0 || st == l.saved {
+		return
+	}
+
+	if err := WriteState(l.s.stateFile, st); err != nil {
+		l.s.logger.Printf("unable to write state file: %s", err)
+		return
+	}
+
+	l.saved = st
+}
+
+// runActions triggers actions without terminating the switch.  The after map
+// holds any dependencies between the actions, as for Schedule.After.
+func (l *loop) runActions(d Details, after map[int][]int, actions []Action) {
+	// actions may run for some time, so let Status report what led to them
+	l.publish()
+
+	l.s.stateLock.Lock()
+	defer l.s.stateLock.Unlock()
+
+	if l.s.deactivate != nil {
+		// trigger actions under the state lock, to make Activate/Deactivate atomic
+		l.s.trigger(l.m.ctx, d, after, actions...)
+	}
+}
+
+// escalate fires each escalation tier whose threshold has been reached for the
+// first time, and resets any tier whose threshold is above the given misses.
+func (l *loop) escalate(misses int) {
+	for i, tier := range l.s.tiers {
+		switch {
+		case misses < tier.Misses:
+			l.fired[i] = false
+
+		case !l.fired[i]:
+			l.fired[i] = true
+			l.s.logger.Printf("escalation tier %d fired [misses=%d]", i+1, misses)
+			l.runActions(
+				Details{
+					TierDetail:   strconv.Itoa(i + 1),
+					MissesDetail: strconv.Itoa(misses),
+				},
+				nil,
+				tier.Actions,
+			)
+		}
+	}
+}
+
+// allowTrigger enforces the switch's maximum triggers within its trigger window.
+func (l *loop) allowTrigger() bool {
+	if l.s.maxTriggers <= 0 {
+		return true
+	}
+
+	cutoff := l.now.Add(-l.s.triggerWindow)
+	recent := l.triggers[:0]
+	for _, t := range l.triggers {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+
+	l.triggers = recent
+	if len(l.triggers) >= l.s.maxTriggers {
+		l.s.logger.Printf("trigger suppressed [triggers=%d] [window=%s]", len(l.triggers), l.s.triggerWindow)
+		return false
+	}
+
+	l.triggers = append(l.triggers, l.now)
+	return true
+}
@@ -0,0 +1,934 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/xmidt-org/chronon"
+)
+
+type LoopSuite struct {
+	DMSSuite
+}
+
+func (suite *LoopSuite) TestRearm() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 0, mockActions.actions()...)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		cfg.Rearm = true
+		cfg.Cooldown = 30 * time.Second
+		s := suite.newSwitch(cfg)
+
+		// not active yet
+		suite.ErrorIs(s.Rearm(), ErrNotActive)
+
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+		suite.ErrorIs(s.Rearm(), ErrNotTripped)
+
+		mockActions[0].ExpectRun().Return(nil).Twice()
+		clock.Add(ttl)
+		synctest.Wait()
+		mockActions[0].AssertNumberOfCalls(suite.T(), "Run", 1)
+
+		// a postpone during the cooldown does not rearm the switch
+		clock.Add(ttl)
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+		clock.Add(ttl)
+		synctest.Wait()
+		mockActions[0].AssertNumberOfCalls(suite.T(), "Run", 1)
+
+		// after the cooldown, a postpone rearms the switch
+		clock.Add(ttl)
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+		suite.ErrorIs(s.Rearm(), ErrNotTripped)
+
+		clock.Add(ttl)
+		synctest.Wait()
+		mockActions[0].AssertNumberOfCalls(suite.T(), "Run", 2)
+
+		// an explicit rearm ignores the cooldown
+		suite.NoError(s.Rearm())
+		suite.ErrorIs(s.Rearm(), ErrNotTripped)
+
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+		suite.ErrorIs(s.Rearm(), ErrNotActive)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestMaxTriggers() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 0, mockActions.actions()...)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		cfg.Rearm = true
+		cfg.MaxTriggers = 2
+		cfg.TriggerWindow = time.Minute
+		cfg.MarkerFile = filepath.Join(t.TempDir(), "dms.marker")
+		s := suite.newSwitch(cfg)
+
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+		mockActions[0].ExpectRun().Return(nil).Times(3)
+
+		// trip the switch three times in quick succession
+		for i := 0; i < 3; i++ {
+			clock.Add(ttl)
+			synctest.Wait()
+			if i < 2 {
+				suite.FileExists(cfg.MarkerFile)
+			} else {
+				// the suppressed trigger is not recorded by a marker
+				suite.NoFileExists(cfg.MarkerFile)
+			}
+
+			suite.NoError(s.Rearm())
+		}
+
+		mockActions[0].AssertNumberOfCalls(suite.T(), "Run", 2)
+
+		// once the window passes, actions run again
+		clock.Add(time.Minute)
+		synctest.Wait()
+		mockActions[0].AssertNumberOfCalls(suite.T(), "Run", 3)
+
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestEscalation() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			warn        = newMockActions(1)
+			page        = newMockActions(2)
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 3, mockActions.actions()...)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		// deliberately out of order
+		cfg.Tiers = []Tier{
+			{Misses: 2, Actions: page.actions()},
+			{Misses: 1, Actions: warn.actions()},
+		}
+
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+		warn[0].ExpectRun().Return(nil).Twice()
+		page.expectRunOnce(nil)
+
+		// the first miss fires the first tier
+		clock.Add(ttl)
+		synctest.Wait()
+		warn[0].AssertNumberOfCalls(suite.T(), "Run", 1)
+
+		// a postpone resets the tiers
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+
+		clock.Add(ttl)
+		synctest.Wait()
+		warn[0].AssertNumberOfCalls(suite.T(), "Run", 2)
+		page[0].AssertNumberOfCalls(suite.T(), "Run", 0)
+
+		clock.Add(ttl)
+		synctest.Wait()
+		page[0].AssertNumberOfCalls(suite.T(), "Run", 1)
+		page[1].AssertNumberOfCalls(suite.T(), "Run", 1)
+		mockActions[0].AssertNumberOfCalls(suite.T(), "Run", 0)
+
+		mockActions.expectRunOnce(nil)
+		clock.Add(ttl)
+		suite.NoError(<-done)
+
+		warn.assertExpectations(suite.T())
+		page.assertExpectations(suite.T())
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestRecover() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			recover     = &mockDetailedAction{mockAction: mockAction{label: "recover"}}
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 3, mockActions.actions()...)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		cfg.RecoverActions = []Action{recover}
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+
+		// a postpone with no outage doesn't recover anything
+		clock.Add(ttl / 2)
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+
+		// the outage starts with the first detected miss
+		clock.Add(ttl)
+		synctest.Wait()
+		clock.Add(ttl)
+		synctest.Wait()
+
+		recover.ExpectRunDetails(Details{MissesDetail: "2", OutageDetail: "15s"}).Return(nil).Once()
+		clock.Add(ttl / 2)
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+
+		// only one recovery per outage
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+		recover.AssertExpectations(suite.T())
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestRecoverAfterRearm() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			recover     = &mockDetailedAction{mockAction: mockAction{label: "recover"}}
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 1, mockActions.actions()...)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		cfg.Rearm = true
+		cfg.RecoverActions = []Action{recover}
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+		mockActions.expectRunOnce(nil)
+		clock.Add(ttl)
+		synctest.Wait()
+
+		recover.ExpectRunDetails(Details{MissesDetail: "1", OutageDetail: "1m0s"}).Return(nil).Once()
+		clock.Add(time.Minute)
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+		suite.ErrorIs(s.Rearm(), ErrNotTripped)
+
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+		recover.AssertExpectations(suite.T())
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestGrace() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 1, mockActions.actions()...)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		cfg.Grace = time.Minute
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		ft := <-onTicker
+		suite.True(ft.When().Equal(suite.now.Add(time.Minute)))
+
+		// postpones during the grace period are accepted, but misses aren't counted
+		clock.Add(ttl / 2)
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+		clock.Add(ttl)
+		synctest.Wait()
+		suite.True(ft.When().Equal(suite.now.Add(time.Minute)))
+
+		// once the grace period ends, the first TTL starts
+		clock.Set(suite.now.Add(time.Minute))
+		synctest.Wait()
+		suite.True(ft.When().Equal(suite.now.Add(time.Minute + ttl)))
+
+		calls := mockActions.expectRunOnce(nil)
+		clock.Add(ttl)
+		mockActions.waitForCalls(suite.T(), time.Second, calls)
+		suite.NoError(<-done)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestArmAfter() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 1, mockActions.actions()...)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		cfg.ArmAfter = 3
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		// without a grace period, the switch waits indefinitely
+		ft := <-onTicker
+		unarmed := ft.When()
+		clock.Add(time.Hour)
+		synctest.Wait()
+
+		// these postpones aren't consecutive, since they are more than a TTL apart
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+		clock.Add(ttl)
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+		clock.Add(ttl)
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+
+		// the third consecutive postpone arms the switch
+		for i := 0; i < 2; i++ {
+			suite.True(ft.When().Equal(unarmed), "The switch should not be armed yet")
+			clock.Add(ttl / 2)
+			suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+			synctest.Wait()
+		}
+
+		suite.True(ft.When().Equal(clock.Now().Add(ttl)), "The switch should be armed")
+
+		calls := mockActions.expectRunOnce(nil)
+		clock.Add(ttl)
+		mockActions.waitForCalls(suite.T(), time.Second, calls)
+		suite.NoError(<-done)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestArmAfterGrace() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 1, mockActions.actions()...)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		cfg.Grace = time.Minute
+		cfg.ArmAfter = 1
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		ft := <-onTicker
+
+		// the first postpone arms the switch before the grace period ends
+		clock.Add(ttl)
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+		suite.True(ft.When().Equal(suite.now.Add(2 * ttl)))
+
+		calls := mockActions.expectRunOnce(nil)
+		clock.Add(ttl)
+		mockActions.waitForCalls(suite.T(), time.Second, calls)
+		suite.NoError(<-done)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestPause() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 1, mockActions.actions()...)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		s := suite.newSwitch(cfg)
+		suite.ErrorIs(s.Pause(PauseRequest{Duration: time.Minute}), ErrPauseReason)
+		suite.ErrorIs(s.Pause(PauseRequest{Reason: "test"}), ErrPauseDuration)
+		suite.ErrorIs(s.Pause(PauseRequest{Reason: "test", Duration: DefaultMaxPause + time.Second}), ErrPauseTooLong)
+		suite.ErrorIs(s.Pause(PauseRequest{Reason: "test", Duration: time.Minute}), ErrNotActive)
+		suite.ErrorIs(s.Resume(), ErrNotActive)
+
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		ft := <-onTicker
+		suite.ErrorIs(s.Resume(), ErrNotPaused)
+
+		// misses are not counted while paused, and postpones are still accepted
+		suite.NoError(s.Pause(PauseRequest{Reason: "test", Duration: time.Minute}))
+		for i := 0; i < 5; i++ {
+			clock.Add(ttl)
+			synctest.Wait()
+		}
+
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+		mockActions[0].AssertNotCalled(suite.T(), "Run")
+
+		// an explicit resume restarts the TTL
+		suite.NoError(s.Resume())
+		suite.ErrorIs(s.Resume(), ErrNotPaused)
+		suite.True(ft.When().Equal(clock.Now().Add(ttl)))
+
+		// a pause ends by itself after its duration
+		suite.NoError(s.Pause(PauseRequest{Reason: "test", Duration: time.Minute}))
+		clock.Add(time.Minute)
+		synctest.Wait()
+		suite.True(ft.When().Equal(clock.Now().Add(ttl)))
+
+		calls := mockActions.expectRunOnce(nil)
+		clock.Add(ttl)
+		mockActions.waitForCalls(suite.T(), time.Second, calls)
+		suite.NoError(<-done)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestMaintenanceWindow() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ny, _ = time.LoadLocation("America/New_York")
+
+			// Sunday at 01:50 in New York
+			start = time.Date(2026, time.January, 4, 1, 50, 0, 0, ny)
+
+			ttl         = 15 * time.Minute
+			mockActions = newMockActions(1)
+			cfg, _      = suite.switchConfig(ttl, 1, mockActions.actions()...)
+			clock       = chronon.NewFakeClock(start)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		w, err := ParseWindow("0 2 * * SUN;2h;America/New_York")
+		suite.Require().NoError(err)
+		cfg.Windows = []Window{w}
+		cfg.Clock = clock
+
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		// the ticker fires when the window opens, before the first deadline
+		ft := <-onTicker
+		suite.True(ft.When().Equal(start.Add(10 * time.Minute)))
+
+		clock.Add(10 * time.Minute)
+		synctest.Wait()
+		suite.True(ft.When().Equal(start.Add(2*time.Hour + 10*time.Minute)))
+
+		// postpones are accepted, and misses are not counted, during the window
+		clock.Add(time.Hour)
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+		mockActions[0].AssertNotCalled(suite.T(), "Run")
+
+		// the TTL restarts when the window closes
+		clock.Add(time.Hour)
+		synctest.Wait()
+		suite.True(ft.When().Equal(clock.Now().Add(ttl)))
+
+		calls := mockActions.expectRunOnce(nil)
+		clock.Add(ttl)
+		mockActions.waitForCalls(suite.T(), time.Second, calls)
+		suite.NoError(<-done)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestStateFile() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 3, mockActions.actions()...)
+			path        = filepath.Join(t.TempDir(), "dms.state")
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		cfg.StateFile = path
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+		clock.Add(ttl / 2)
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+
+		st, err := ReadState(path)
+		suite.Require().NoError(err)
+		suite.True(st.LastPostpone.Equal(suite.now.Add(ttl / 2)))
+		suite.True(st.Deadline.Equal(suite.now.Add(ttl + ttl/2)))
+
+		clock.Add(ttl)
+		synctest.Wait()
+		st, err = ReadState(path)
+		suite.Require().NoError(err)
+		suite.Equal(1, st.Misses)
+
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+
+		// a restarted switch picks up the countdown rather than starting a new TTL
+		clock.Add(ttl / 2)
+		s = suite.newSwitch(cfg)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		ft := <-onTicker
+		suite.True(ft.When().Equal(suite.now.Add(2*ttl + ttl/2)))
+
+		// the miss before the restart still counts
+		calls := mockActions.expectRunOnce(nil)
+		clock.Add(2 * ttl)
+		mockActions.waitForCalls(suite.T(), time.Second, calls)
+		suite.NoError(<-done)
+		mockActions.assertExpectations(suite.T())
+
+		st, err = ReadState(path)
+		suite.Require().NoError(err)
+		suite.True(st.Triggered)
+		suite.Equal(3, st.Misses)
+	})
+}
+
+func (suite *LoopSuite) TestStateFileTripped() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 1, mockActions.actions()...)
+			path        = filepath.Join(t.TempDir(), "dms.state")
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		suite.Require().NoError(WriteState(path, State{Triggered: true, TriggeredAt: suite.now}))
+		cfg.StateFile = path
+		cfg.Rearm = true
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		// the switch starts tripped, so no actions run
+		<-onTicker
+		clock.Add(10 * ttl)
+		synctest.Wait()
+		suite.NoError(s.Rearm())
+
+		// the state is persisted once the loop has handled the command
+		synctest.Wait()
+		st, err := ReadState(path)
+		suite.Require().NoError(err)
+		suite.False(st.Triggered)
+		suite.True(st.Deadline.Equal(clock.Now().Add(ttl)))
+
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestMarkerFile() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 1, mockActions.actions()...)
+			path        = filepath.Join(t.TempDir(), "dms.marker")
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		cfg.MarkerFile = path
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+
+		// the marker is written before the actions run
+		mockActions[0].ExpectRun().Run(func(mock.Arguments) {
+			m, err := ReadMarker(path)
+			suite.NoError(err)
+			suite.True(m.TriggeredAt.Equal(suite.now.Add(ttl)))
+			suite.Equal(1, m.Misses)
+		}).Return(nil).Once()
+
+		clock.Add(ttl)
+		suite.NoError(<-done)
+		mockActions[0].AssertNumberOfCalls(suite.T(), "Run", 1)
+
+		// a restarted switch is held, and does not trigger again
+		s = suite.newSwitch(cfg)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+		suite.ErrorIs(s.Pause(PauseRequest{Reason: "test", Duration: time.Minute}), ErrNotArmed)
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		clock.Add(10 * ttl)
+		synctest.Wait()
+		mockActions[0].AssertNumberOfCalls(suite.T(), "Run", 1)
+
+		// once the marker is cleared, the next postpone arms the switch
+		suite.Require().NoError(os.Remove(path))
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+		suite.NoError(s.Pause(PauseRequest{Reason: "test", Duration: time.Minute}))
+
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestMarkerFileRearm() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 1, mockActions.actions()...)
+			path        = filepath.Join(t.TempDir(), "dms.marker")
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		cfg.MarkerFile = path
+		cfg.Rearm = true
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+		mockActions[0].ExpectRun().Return(nil).Once()
+		clock.Add(ttl)
+		synctest.Wait()
+
+		_, err := ReadMarker(path)
+		suite.NoError(err)
+
+		// re-arming removes the marker, since the switch may trigger again
+		suite.NoError(s.Rearm())
+		_, err = ReadMarker(path)
+		suite.ErrorIs(err, os.ErrNotExist)
+
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func TestLoop(t *testing.T) {
+	suite.Run(t, new(LoopSuite))
+}
+
+func (suite *LoopSuite) TestTrigger() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 3, mockActions.actions()...)
+			path        = filepath.Join(t.TempDir(), "dms.marker")
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+			tr          = TriggerRequest{Source: "ops", Reason: "runbook test", RemoteAddr: "127.0.0.1:1234"}
+		)
+
+		cfg.Grace = time.Hour
+		cfg.MarkerFile = path
+		s := suite.newSwitch(cfg)
+		suite.ErrorIs(s.Trigger(tr), ErrNotActive)
+		suite.ErrorIs(s.Trigger(TriggerRequest{Source: "ops"}), ErrTriggerReason)
+
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		// a manual trigger ignores the grace period and the remaining misses
+		<-onTicker
+		mockActions[0].ExpectRun().Return(nil).Once()
+		suite.NoError(s.Trigger(tr))
+		suite.NoError(<-done)
+		suite.ErrorIs(s.Trigger(tr), ErrNotActive)
+
+		m, err := ReadMarker(path)
+		suite.Require().NoError(err)
+		suite.Equal(Details{SourceDetail: "ops", ReasonDetail: "runbook test"}, m.Details)
+
+		// a switch held by the marker cannot be triggered again
+		s = suite.newSwitch(cfg)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+		suite.ErrorIs(s.Trigger(tr), ErrHeld)
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestTriggerRearm() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 1, mockActions.actions()...)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+			tr          = TriggerRequest{Source: "ops", Reason: "test"}
+		)
+
+		cfg.Rearm = true
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		// a paused switch can still be triggered, after which it is tripped
+		ft := <-onTicker
+		suite.NoError(s.Pause(PauseRequest{Reason: "test", Duration: time.Minute}))
+		mockActions[0].ExpectRun().Return(nil).Twice()
+		suite.NoError(s.Trigger(tr))
+		mockActions[0].AssertNumberOfCalls(suite.T(), "Run", 1)
+		suite.ErrorIs(s.Trigger(tr), ErrTripped)
+		suite.Equal(ModeTripped, s.Status().Mode)
+
+		// once rearmed, the switch counts misses as usual
+		suite.NoError(s.Rearm())
+		suite.True(ft.When().Equal(clock.Now().Add(ttl)))
+		clock.Add(ttl)
+		synctest.Wait()
+		mockActions[0].AssertNumberOfCalls(suite.T(), "Run", 2)
+
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestUnhealthy() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 3, mockActions.actions()...)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+			healthy     = &Heartbeat{Status: "ok", Metrics: map[string]any{"queue_depth": 12.0}}
+			unhealthy   = &Heartbeat{Status: "ok", Metrics: map[string]any{"queue_depth": 5000.0}}
+		)
+
+		cfg.Rules, _ = ParseRules(CommandLine{Rule: []string{`status == "ok"`, "queue_depth < 1000"}})
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+
+		// an unhealthy heartbeat, or none at all, doesn't postpone the switch
+		clock.Add(ttl / 2)
+		suite.True(s.Postpone(PostponeRequest{Source: "test", Heartbeat: unhealthy}))
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+		suite.Nil(s.Status().LastPostpone)
+
+		clock.Add(ttl / 2)
+		synctest.Wait()
+		suite.Equal(1, s.Status().Misses)
+
+		// a healthy heartbeat postpones as usual
+		suite.True(s.Postpone(PostponeRequest{Source: "test", Heartbeat: healthy}))
+		synctest.Wait()
+		st := s.Status()
+		suite.Zero(st.Misses)
+		suite.Require().NotNil(st.LastPostpone)
+		suite.Equal("test", st.LastPostpone.Source)
+
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestUnhealthyTrigger() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl        = 10 * time.Second
+			action     = &mockDetailedAction{mockAction: mockAction{label: "action"}}
+			cfg, clock = suite.switchConfig(ttl, 3, action)
+			done       = make(chan error)
+			onTicker   = make(chan chronon.FakeTicker, 1)
+			unhealthy  = PostponeRequest{Source: "test", Heartbeat: &Heartbeat{Status: "degraded"}}
+		)
+
+		cfg.Grace = time.Minute
+		cfg.Rules, _ = ParseRules(CommandLine{Rule: []string{`status == "ok"`}})
+		cfg.Unhealthy = UnhealthyTrigger
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		// an unarmed switch doesn't trigger
+		<-onTicker
+		suite.True(s.Postpone(unhealthy))
+		synctest.Wait()
+		suite.Equal(ModeUnarmed, s.Status().Mode)
+
+		clock.Add(time.Minute)
+		synctest.Wait()
+		suite.Equal(ModeArmed, s.Status().Mode)
+
+		// once armed, an unhealthy heartbeat triggers immediately
+		action.ExpectRunDetails(Details{SourceDetail: "test", UnhealthyDetail: `status == "ok"`}).Return(nil).Once()
+		suite.True(s.Postpone(unhealthy))
+		suite.NoError(<-done)
+		suite.Equal(StateTriggered, s.Status().State)
+		action.AssertExpectations(suite.T())
+	})
+}
+
+func (suite *LoopSuite) TestAbortAndKeepRunning() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl      = 10 * time.Second
+			failing  = retryingAction{mockAction: &mockAction{label: "failing"}, policy: RetryPolicy{OnFailure: FailureAbortAndKeepRunning}}
+			skipped  = newMockActions(1)
+			cfg, clk = suite.switchConfig(ttl, 1, failing, skipped[0])
+			done     = make(chan error)
+			onTicker = make(chan chronon.FakeTicker, 1)
+		)
+
+		cfg.MarkerFile = filepath.Join(t.TempDir(), "dms.marker")
+		cfg.StateFile = filepath.Join(t.TempDir(), "dms.state")
+		s := suite.newSwitch(cfg)
+		clk.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		// the failure aborts the trigger, so the switch re-arms rather than stopping
+		<-onTicker
+		failing.ExpectRun().Return(errors.New("expected")).Twice()
+		clk.Add(ttl)
+		synctest.Wait()
+		failing.AssertNumberOfCalls(suite.T(), "Run", 1)
+
+		st := s.Status()
+		suite.Equal(StateActive, st.State)
+		suite.Equal(ModeArmed, st.Mode)
+
+		// the marker records that actions ran, but the state is no longer triggered
+		suite.FileExists(cfg.MarkerFile)
+		saved, err := ReadState(cfg.StateFile)
+		suite.Require().NoError(err)
+		suite.False(saved.Triggered)
+		suite.True(saved.Deadline.Equal(clk.Now().Add(ttl)))
+
+		// postpones are still accepted, and the switch keeps counting
+		clk.Add(ttl / 2)
+		suite.True(s.Postpone(PostponeRequest{Source: "test"}))
+		synctest.Wait()
+		suite.True(s.Status().Deadline.Equal(clk.Now().Add(ttl)))
+
+		clk.Add(ttl)
+		synctest.Wait()
+		failing.AssertNumberOfCalls(suite.T(), "Run", 2)
+
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+		failing.AssertExpectations(suite.T())
+		skipped.assertExpectations(suite.T())
+	})
+}
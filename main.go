@@ -12,12 +12,16 @@ import (
 
 func newApp(args []string) *fx.App {
 	return fx.New(
-		parseCommandLine(args),
 		provideLogger(os.Stdout),
-		provideActions(),
-		provideSwitchConfig(),
-		provideSwitch(),
-		provideHTTP(),
+		parseCommandLine(
+			args,
+			provideEvents(),
+			provideActions(),
+			provideSwitchConfig(),
+			provideSwitch(),
+			provideRegistry(),
+			provideHTTP(),
+		),
 	)
 }
 
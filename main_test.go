@@ -21,13 +21,29 @@ func (suite *NewAppSuite) SetupSuite() {
 	suite.validParameters = [][]string{
 		{"--exec", "echo 'hi'"},
 		{"--exec", "echo 'hi'", "--ttl", "10s", "--misses", "4"},
+		{"--exec", "echo 'hi'", "--min-ttl", "5s", "--max-ttl", "2h"},
 		{"--exec", "echo 'hi'", "--exec", "echo 'another'", "--ttl", "12h", "--misses", "2", "--debug"},
+		{"run", "--exec", "echo 'hi'"},
+		{"--exec", "echo 'hi'", "--dry-run"},
+		{"--exec", "echo 'hi'", "--admin-token", "ops=secret"},
+		{"--exec", "echo 'hi'", "--rule", `status == "ok"`, "--rule", "queue_depth < 1000", "--unhealthy", "trigger"},
+		{"--exec", "echo 'hi'", "--min-postpones", "8", "--budget-window", "10"},
+		{"--exec", `echo 'hello, world'`, "--exec", `["echo", "hello"]`, "--shell", "--expand-env"},
+		{"--exec", "echo 'hi'", "--action-timeout", "30s", "--trigger-timeout", "1m", "--kill-grace", "1s"},
+		{"--exec", "echo 'hi'", "--exec", "echo 'bye'", "--retries", "1", "--exec-policy", "2=retries:3,on-failure:stop-remaining"},
+		{"clear-marker", "/var/lib/dms/marker.json"},
 	}
 
 	suite.invalidParameters = [][]string{
 		{},
 		{"--foobar"},
 		{"--exec", "echo 'hi'", "--foobar"},
+		{"clear-marker"},
+		{"--exec", "echo 'hi'", "--admin-token", "secret"},
+		{"--exec", "echo 'hi'", "--rule", "queue_depth"},
+		{"--exec", "echo 'hi'", "--unhealthy", "nosuch"},
+		{"--exec", "echo 'hi"},
+		{"--exec", "echo 'hi'", "--exec-policy", "2=retries:3"},
 	}
 }
 
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/fx"
+)
+
+// Marker records that a switch triggered.  A marker is written before a switch
+// runs its actions, and a switch that finds a marker when activated refuses to
+// arm until the marker is cleared.  This prevents actions from running again
+// after a supervisor restarts dms.
+type Marker struct {
+	// TriggeredAt is the time at which the switch triggered.
+	TriggeredAt time.Time `json:"triggeredAt"`
+
+	// Misses is the number of consecutive misses when the switch triggered.
+	Misses int `json:"misses"`
+
+	// Details describes why the switch triggered.
+	Details Details `json:"details,omitempty"`
+}
+
+// String returns a human-readable representation of this marker.
+func (m Marker) String() string {
+	s := fmt.Sprintf("[triggeredAt=%s] [misses=%d]", m.TriggeredAt.Format(time.RFC3339), m.Misses)
+	if len(m.Details) > 0 {
+		s += " " + m.Details.String()
+	}
+
+	return s
+}
+
+// ReadMarker reads a Marker from the given file.  If the file does not exist,
+// this function returns an error that satisfies errors.Is(err, os.ErrNotExist).
+func ReadMarker(path string) (m Marker, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err == nil {
+		err = json.Unmarshal(data, &m)
+	}
+
+	return
+}
+
+// WriteMarker atomically writes a Marker to the given file.
+func WriteMarker(path string, m Marker) error {
+	return writeJSON(path, m)
+}
+
+// ClearMarkerCommand is the command line for clearing a trigger marker.
+type ClearMarkerCommand struct {
+	MarkerFile string `arg:"" name:"marker-file" type:"path" help:"the trigger marker file to clear"`
+}
+
+// provideClearMarker creates an fx.Option that clears a trigger marker and then
+// shuts down the enclosing application.
+func provideClearMarker() fx.Option {
+	return fx.Invoke(
+		func(cmd ClearMarkerCommand, l Logger, lifecycle fx.Lifecycle, s fx.Shutdowner) {
+			lifecycle.Append(fx.Hook{
+				OnStart: func(context.Context) error {
+					m, err := ReadMarker(cmd.MarkerFile)
+					switch {
+					case errors.Is(err, os.ErrNotExist):
+						l.Printf("no trigger marker at %s", cmd.MarkerFile)
+						return s.Shutdown()
+
+					case err != nil:
+						// an unreadable marker is still cleared
+						l.Printf("unable to read trigger marker: %s", err)
+					}
+
+					if err = os.Remove(cmd.MarkerFile); err != nil {
+						return err
+					}
+
+					l.Printf("cleared trigger marker %s %s", cmd.MarkerFile, m)
+					return s.Shutdown()
+				},
+			})
+		},
+	)
+}
@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+)
+
+type MarkerSuite struct {
+	DMSSuite
+}
+
+func (suite *MarkerSuite) TestReadWrite() {
+	var (
+		path = filepath.Join(suite.T().TempDir(), "dms.marker")
+		now  = time.Date(2026, time.January, 4, 2, 0, 0, 0, time.UTC)
+
+		expected = Marker{
+			TriggeredAt: now,
+			Misses:      3,
+			Details:     Details{SilentDetail: "a"},
+		}
+	)
+
+	_, err := ReadMarker(path)
+	suite.ErrorIs(err, os.ErrNotExist)
+
+	suite.Require().NoError(WriteMarker(path, expected))
+	actual, err := ReadMarker(path)
+	suite.Require().NoError(err)
+	suite.Equal(expected, actual)
+	suite.Equal("[triggeredAt=2026-01-04T02:00:00Z] [misses=3] [silent=a]", actual.String())
+	suite.Equal("[triggeredAt=2026-01-04T02:00:00Z] [misses=0]", Marker{TriggeredAt: now}.String())
+}
+
+// clearMarker runs the clear-marker command line against the given path.
+func (suite *MarkerSuite) clearMarker(path string) {
+	app := fxtest.New(
+		suite.T(),
+		suite.provideLogger(),
+		parseCommandLine(
+			[]string{"clear-marker", path},
+			fx.Invoke(func() {
+				suite.Fail("run options should not be used by clear-marker")
+			}),
+		),
+	)
+
+	app.RequireStart()
+	select {
+	case <-app.Wait():
+	case <-time.After(time.Second):
+		suite.Fail("clear-marker did not shut down")
+	}
+
+	app.RequireStop()
+}
+
+func (suite *MarkerSuite) TestClearMarker() {
+	suite.Run("Exists", func() {
+		path := filepath.Join(suite.T().TempDir(), "dms.marker")
+		suite.Require().NoError(WriteMarker(path, Marker{TriggeredAt: suite.now}))
+		suite.clearMarker(path)
+
+		_, err := os.Stat(path)
+		suite.ErrorIs(err, os.ErrNotExist)
+	})
+
+	suite.Run("Unreadable", func() {
+		path := filepath.Join(suite.T().TempDir(), "dms.marker")
+		suite.Require().NoError(os.WriteFile(path, []byte(`{`), 0600))
+		suite.clearMarker(path)
+
+		_, err := os.Stat(path)
+		suite.ErrorIs(err, os.ErrNotExist)
+	})
+
+	suite.Run("Missing", func() {
+		suite.clearMarker(filepath.Join(suite.T().TempDir(), "dms.marker"))
+	})
+}
+
+func TestMarker(t *testing.T) {
+	suite.Run(t, new(MarkerSuite))
+}
@@ -27,6 +27,131 @@ func (m *mockPostponer) ExpectPostpone(request interface{}) *mock.Call {
 	return m.On("Postpone", request)
 }
 
+type mockRearmer struct {
+	mock.Mock
+}
+
+var _ Rearmer = (*mockRearmer)(nil)
+
+func (m *mockRearmer) Rearm() error {
+	return m.Called().Error(0)
+}
+
+func (m *mockRearmer) ExpectRearm() *mock.Call {
+	return m.On("Rearm")
+}
+
+type mockPauser struct {
+	mock.Mock
+}
+
+var _ Pauser = (*mockPauser)(nil)
+
+func (m *mockPauser) Pause(pr PauseRequest) error {
+	return m.Called(pr).Error(0)
+}
+
+func (m *mockPauser) ExpectPause(request interface{}) *mock.Call {
+	return m.On("Pause", request)
+}
+
+func (m *mockPauser) Resume() error {
+	return m.Called().Error(0)
+}
+
+func (m *mockPauser) ExpectResume() *mock.Call {
+	return m.On("Resume")
+}
+
+type mockTriggerer struct {
+	mock.Mock
+}
+
+var _ Triggerer = (*mockTriggerer)(nil)
+
+func (m *mockTriggerer) Trigger(tr TriggerRequest) error {
+	return m.Called(tr).Error(0)
+}
+
+func (m *mockTriggerer) ExpectTrigger(request interface{}) *mock.Call {
+	return m.On("Trigger", request)
+}
+
+type mockDeactivator struct {
+	mock.Mock
+}
+
+var _ Deactivator = (*mockDeactivator)(nil)
+
+func (m *mockDeactivator) StandDown(dr DeactivateRequest) (Status, error) {
+	args := m.Called(dr)
+	return args.Get(0).(Status), args.Error(1)
+}
+
+func (m *mockDeactivator) ExpectStandDown(request interface{}) *mock.Call {
+	return m.On("StandDown", request)
+}
+
+type mockAborter struct {
+	mock.Mock
+}
+
+var _ Aborter = (*mockAborter)(nil)
+
+func (m *mockAborter) Abort() bool {
+	return m.Called().Bool(0)
+}
+
+func (m *mockAborter) ExpectAbort() *mock.Call {
+	return m.On("Abort")
+}
+
+type mockSwitchListener struct {
+	mock.Mock
+}
+
+var _ SwitchListener = (*mockSwitchListener)(nil)
+
+func (m *mockSwitchListener) OnPostpone(name string, t time.Time, pr PostponeRequest) {
+	m.Called(name, t, pr)
+}
+
+func (m *mockSwitchListener) ExpectOnPostpone(name, t, pr interface{}) *mock.Call {
+	return m.On("OnPostpone", name, t, pr)
+}
+
+func (m *mockSwitchListener) OnMiss(name string, t time.Time, misses, maxMisses int) {
+	m.Called(name, t, misses, maxMisses)
+}
+
+func (m *mockSwitchListener) ExpectOnMiss(name, t, misses, maxMisses interface{}) *mock.Call {
+	return m.On("OnMiss", name, t, misses, maxMisses)
+}
+
+func (m *mockSwitchListener) OnTrigger(name string, t time.Time, misses int, d Details) {
+	m.Called(name, t, misses, d)
+}
+
+func (m *mockSwitchListener) ExpectOnTrigger(name, t, misses, d interface{}) *mock.Call {
+	return m.On("OnTrigger", name, t, misses, d)
+}
+
+func (m *mockSwitchListener) OnDeactivate(name string, t time.Time) {
+	m.Called(name, t)
+}
+
+func (m *mockSwitchListener) ExpectOnDeactivate(name, t interface{}) *mock.Call {
+	return m.On("OnDeactivate", name, t)
+}
+
+func (m *mockSwitchListener) OnActionResult(name string, t time.Time, a Action, err error) {
+	m.Called(name, t, a, err)
+}
+
+func (m *mockSwitchListener) ExpectOnActionResult(name, t, a, err interface{}) *mock.Call {
+	return m.On("OnActionResult", name, t, a, err)
+}
+
 type mockAction struct {
 	mock.Mock
 	label string
@@ -45,6 +170,21 @@ func (m *mockAction) ExpectRun() *mock.Call {
 	return m.On("Run")
 }
 
+// mockDetailedAction is a mocked DetailedAction
+type mockDetailedAction struct {
+	mockAction
+}
+
+var _ DetailedAction = (*mockDetailedAction)(nil)
+
+func (m *mockDetailedAction) RunDetails(d Details) error {
+	return m.Called(d).Error(0)
+}
+
+func (m *mockDetailedAction) ExpectRunDetails(d interface{}) *mock.Call {
+	return m.On("RunDetails", d)
+}
+
 // mockActions is a slice of mocked Action instances with some useful behavior
 type mockActions []*mockAction
 
@@ -0,0 +1,541 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// verdict is the outcome of a policy checking for missed postpones.
+type verdict struct {
+	// misses is the current number of consecutive missed intervals.
+	misses int
+
+	// trigger indicates whether the switch should trigger its actions.
+	trigger bool
+
+	// details describes why the switch is triggering.  This is passed
+	// along to the actions.
+	details Details
+}
+
+// policy decides when a Switch has gone without postpones for long enough
+// to trigger.  A policy is only ever used from within the Activate loop,
+// so implementations need not be safe for concurrent use.
+type policy interface {
+	// start begins tracking intervals from the given time.
+	start(now time.Time)
+
+	// restore resumes tracking intervals from a persisted deadline and miss count.
+	restore(deadline time.Time, misses int)
+
+	// postpone records a postpone request received at the given time.
+	postpone(now time.Time, pr PostponeRequest)
+
+	// deadline returns the next time at which expire must be called.
+	deadline() time.Time
+
+	// misses returns the current number of consecutive missed intervals.
+	misses() int
+
+	// expire records any intervals that have elapsed as of the given time.
+	expire(now time.Time) verdict
+}
+
+// consecutivePolicy is the default policy.  Any postpone, regardless of source,
+// resets the miss count, and the switch triggers after maxMisses consecutive
+// intervals with no postpones.
+type consecutivePolicy struct {
+	logger    Logger
+	ttl       time.Duration
+	maxMisses int
+
+	next      time.Time
+	missCount int
+}
+
+func (cp *consecutivePolicy) start(now time.Time) {
+	cp.next = now.Add(cp.ttl)
+	cp.missCount = 0
+}
+
+func (cp *consecutivePolicy) restore(deadline time.Time, misses int) {
+	cp.next = deadline
+	cp.missCount = misses
+}
+
+func (cp *consecutivePolicy) postpone(now time.Time, pr PostponeRequest) {
+	cp.next = now.Add(pr.ttl(cp.ttl))
+	cp.missCount = 0
+}
+
+func (cp *consecutivePolicy) deadline() time.Time {
+	return cp.next
+}
+
+func (cp *consecutivePolicy) misses() int {
+	return cp.missCount
+}
+
+func (cp *consecutivePolicy) expire(now time.Time) (v verdict) {
+	for !now.Before(cp.next) && !v.trigger {
+		cp.next = cp.next.Add(cp.ttl)
+		cp.missCount++
+		cp.logger.Printf("missed postpone update [misses=%d]", cp.missCount)
+		v.trigger = cp.missCount >= cp.maxMisses
+	}
+
+	v.misses = cp.missCount
+	return
+}
+
+// budgetPolicy is a sliding window miss budget.  Each of the switch's TTL
+// intervals is kept if a postpone from any source arrived during it, or if a
+// postpone's TTL covers it.  Otherwise, the interval is missed.  The switch
+// triggers as soon as fewer than minPostpones of the most recent intervals
+// were kept, so a sender that misses every other interval still triggers it.
+//
+// The miss count is still the number of consecutive missed intervals, which
+// drives escalation tiers and recovery.
+type budgetPolicy struct {
+	logger       Logger
+	ttl          time.Duration
+	minPostpones int
+
+	// intervals is a ring of the most recent intervals, where true means missed.
+	// Intervals before the policy started are treated as kept.
+	intervals []bool
+	pos       int
+	missed    int
+
+	// last is the time of the most recent postpone, and until is the end of
+	// the TTL that postpone requested.
+	last  time.Time
+	until time.Time
+
+	next      time.Time
+	missCount int
+}
+
+// newBudgetPolicy creates a budgetPolicy over the given number of intervals.
+func newBudgetPolicy(l Logger, ttl time.Duration, minPostpones, intervals int) *budgetPolicy {
+	return &budgetPolicy{
+		logger:       l,
+		ttl:          ttl,
+		minPostpones: minPostpones,
+		intervals:    make([]bool, intervals),
+	}
+}
+
+// status describes the policy and the number of kept intervals in the window.
+func (bp *budgetPolicy) status() string {
+	return fmt.Sprintf(
+		"[policy=budget] [postpones=%d] [intervals=%d] [minPostpones=%d]",
+		bp.kept(), len(bp.intervals), bp.minPostpones,
+	)
+}
+
+// kept returns the number of intervals in the window that had a postpone.
+func (bp *budgetPolicy) kept() int {
+	return len(bp.intervals) - bp.missed
+}
+
+// record adds an interval to the window, dropping the oldest interval.
+func (bp *budgetPolicy) record(missed bool) {
+	if bp.intervals[bp.pos] {
+		bp.missed--
+	}
+
+	bp.intervals[bp.pos] = missed
+	if missed {
+		bp.missed++
+	}
+
+	bp.pos = (bp.pos + 1) % len(bp.intervals)
+}
+
+// reset treats every interval in the window as kept.
+func (bp *budgetPolicy) reset() {
+	clear(bp.intervals)
+	bp.pos = 0
+	bp.missed = 0
+	bp.last = time.Time{}
+	bp.until = time.Time{}
+}
+
+func (bp *budgetPolicy) start(now time.Time) {
+	bp.reset()
+	bp.next = now.Add(bp.ttl)
+	bp.missCount = 0
+}
+
+// restore resumes the window.  Since the window itself is not persisted, only the
+// consecutive misses are known, so every earlier interval is treated as kept.
+func (bp *budgetPolicy) restore(deadline time.Time, misses int) {
+	bp.reset()
+	bp.next = deadline
+	bp.missCount = misses
+	for i := 0; i < misses && i < len(bp.intervals); i++ {
+		bp.record(true)
+	}
+}
+
+func (bp *budgetPolicy) postpone(now time.Time, pr PostponeRequest) {
+	bp.last = now
+	bp.until = now.Add(pr.ttl(bp.ttl))
+	bp.missCount = 0
+}
+
+func (bp *budgetPolicy) deadline() time.Time {
+	return bp.next
+}
+
+func (bp *budgetPolicy) misses() int {
+	return bp.missCount
+}
+
+func (bp *budgetPolicy) expire(now time.Time) (v verdict) {
+	for !now.Before(bp.next) && !v.trigger {
+		check := bp.next
+		bp.next = bp.next.Add(bp.ttl)
+		if bp.last.After(check.Add(-bp.ttl)) || bp.until.After(check) {
+			bp.record(false)
+			continue
+		}
+
+		bp.record(true)
+		bp.missCount++
+		bp.logger.Printf("missed postpone update %s [misses=%d]", bp.status(), bp.missCount)
+		v.trigger = bp.kept() < bp.minPostpones
+	}
+
+	v.misses = bp.missCount
+	if v.trigger {
+		v.details = Details{
+			PostponesDetail: strconv.Itoa(bp.kept()),
+			IntervalsDetail: strconv.Itoa(len(bp.intervals)),
+		}
+	}
+
+	return
+}
+
+// sourceState is the liveness state of a single expected source.
+type sourceState struct {
+	SourceConfig
+
+	next   time.Time
+	misses int
+}
+
+// sourcesPolicy tracks each of a set of expected sources independently.  The
+// switch triggers as soon as any one source exceeds its own miss budget.
+// Postpones from unexpected sources are logged but otherwise ignored.
+type sourcesPolicy struct {
+	logger  Logger
+	sources []*sourceState
+}
+
+// newSourcesPolicy creates a sourcesPolicy, applying the switch's TTL and
+// maxMisses to any source that does not specify its own.
+func newSourcesPolicy(l Logger, ttl time.Duration, maxMisses int, sources []SourceConfig) *sourcesPolicy {
+	sp := &sourcesPolicy{
+		logger:  l,
+		sources: make([]*sourceState, 0, len(sources)),
+	}
+
+	for _, sc := range sources {
+		if sc.TTL <= 0 {
+			sc.TTL = ttl
+		}
+
+		if sc.MaxMisses <= 0 {
+			sc.MaxMisses = maxMisses
+		}
+
+		if sc.MaxMisses <= 0 {
+			// as with consecutivePolicy, a nonpositive budget triggers on the first miss
+			sc.MaxMisses = 1
+		}
+
+		sp.sources = append(sp.sources, &sourceState{SourceConfig: sc})
+	}
+
+	return sp
+}
+
+func (sp *sourcesPolicy) start(now time.Time) {
+	for _, ss := range sp.sources {
+		ss.next = now.Add(ss.TTL)
+		ss.misses = 0
+	}
+}
+
+// restore applies the persisted deadline to every source.  Per-source state is
+// not persisted, so the miss count is only applied to sources whose budget allows it.
+func (sp *sourcesPolicy) restore(deadline time.Time, misses int) {
+	for _, ss := range sp.sources {
+		ss.next = deadline
+		ss.misses = min(misses, ss.MaxMisses-1)
+	}
+}
+
+func (sp *sourcesPolicy) postpone(now time.Time, pr PostponeRequest) {
+	for _, ss := range sp.sources {
+		if ss.Name == pr.Source {
+			ss.next = now.Add(pr.ttl(ss.TTL))
+			ss.misses = 0
+			return
+		}
+	}
+
+	sp.logger.Printf("unexpected source %s", pr)
+}
+
+func (sp *sourcesPolicy) misses() (m int) {
+	for _, ss := range sp.sources {
+		if ss.misses > m {
+			m = ss.misses
+		}
+	}
+
+	return
+}
+
+func (sp *sourcesPolicy) deadline() (d time.Time) {
+	for i, ss := range sp.sources {
+		if i == 0 || ss.next.Before(d) {
+			d = ss.next
+		}
+	}
+
+	return
+}
+
+func (sp *sourcesPolicy) expire(now time.Time) (v verdict) {
+	var silent []string
+	for _, ss := range sp.sources {
+		for !now.Before(ss.next) && ss.misses < ss.MaxMisses {
+			ss.next = ss.next.Add(ss.TTL)
+			ss.misses++
+			sp.logger.Printf("missed postpone update [source=%s] [misses=%d]", ss.Name, ss.misses)
+		}
+
+		if ss.misses > v.misses {
+			v.misses = ss.misses
+		}
+
+		if ss.misses >= ss.MaxMisses {
+			silent = append(silent, ss.Name)
+		}
+	}
+
+	if len(silent) > 0 {
+		v.trigger = true
+		v.details = Details{SilentDetail: strings.Join(silent, ",")}
+	}
+
+	return
+}
+
+// quorumSource is the liveness state of a single source in a quorumPolicy.
+type quorumSource struct {
+	name   string
+	ttl    time.Duration
+	weight int
+
+	// until is the time this source stops being live, given its last postpone
+	until time.Time
+	seen  bool
+}
+
+// live tests if this source has postponed within its TTL as of the given time.
+func (qs *quorumSource) live(now time.Time) bool {
+	return qs.seen && now.Before(qs.until)
+}
+
+// quorumPolicy requires a weighted quorum of distinct sources to have postponed
+// within their TTLs.  The quorum is checked on each of the switch's TTL intervals,
+// and each check that falls short counts as a miss.
+//
+// If expected sources are configured, only those sources count toward the quorum.
+// Otherwise, every distinct source counts with a weight of 1.  Such a source is
+// forgotten once it has been silent for a further TTL after it stopped being live,
+// and at most MaxQuorumSources of them are tracked at once.
+type quorumPolicy struct {
+	logger    Logger
+	ttl       time.Duration
+	maxMisses int
+	quorum    int
+
+	// expected indicates whether only configured sources are allowed
+	expected bool
+	sources  []*quorumSource
+
+	next      time.Time
+	missCount int
+}
+
+func newQuorumPolicy(l Logger, ttl time.Duration, maxMisses, quorum int, sources []SourceConfig) *quorumPolicy {
+	qp := &quorumPolicy{
+		logger:    l,
+		ttl:       ttl,
+		maxMisses: maxMisses,
+		quorum:    quorum,
+		expected:  len(sources) > 0,
+	}
+
+	for _, sc := range sources {
+		qp.sources = append(qp.sources, &quorumSource{
+			name:   sc.Name,
+			ttl:    sc.TTL,
+			weight: sc.Weight,
+		})
+	}
+
+	for _, qs := range qp.sources {
+		if qs.ttl <= 0 {
+			qs.ttl = ttl
+		}
+
+		if qs.weight <= 0 {
+			qs.weight = 1
+		}
+	}
+
+	return qp
+}
+
+// status describes the policy and the current live count, as of the given time.
+func (qp *quorumPolicy) status(now time.Time) string {
+	return fmt.Sprintf("[policy=quorum] [quorum=%d] [live=%d]", qp.quorum, qp.live(now))
+}
+
+// live computes the total weight of sources that are currently live.
+func (qp *quorumPolicy) live(now time.Time) (total int) {
+	for _, qs := range qp.sources {
+		if qs.live(now) {
+			total += qs.weight
+		}
+	}
+
+	return
+}
+
+func (qp *quorumPolicy) start(now time.Time) {
+	qp.next = now.Add(qp.ttl)
+	qp.missCount = 0
+	for _, qs := range qp.sources {
+		qs.seen = false
+	}
+}
+
+// restore resumes checking the quorum.  Since per-source state is not persisted,
+// no source is live until it postpones again.
+func (qp *quorumPolicy) restore(deadline time.Time, misses int) {
+	qp.next = deadline
+	qp.missCount = misses
+	for _, qs := range qp.sources {
+		qs.seen = false
+	}
+}
+
+func (qp *quorumPolicy) postpone(now time.Time, pr PostponeRequest) {
+	var source *quorumSource
+	for _, qs := range qp.sources {
+		if qs.name == pr.Source {
+			source = qs
+			break
+		}
+	}
+
+	switch {
+	case source == nil && qp.expected:
+		qp.logger.Printf("unexpected source %s", pr)
+		return
+
+	case source == nil:
+		qp.forget(now)
+		if len(qp.sources) >= MaxQuorumSources {
+			qp.logger.Printf("too many sources, ignoring %s [max=%d]", pr, MaxQuorumSources)
+			return
+		}
+
+		source = &quorumSource{name: pr.Source, ttl: qp.ttl, weight: 1}
+		qp.sources = append(qp.sources, source)
+	}
+
+	source.until = now.Add(pr.ttl(source.ttl))
+	source.seen = true
+	if qp.live(now) >= qp.quorum {
+		qp.missCount = 0
+	}
+
+	qp.logger.Printf("quorum %s", qp.status(now))
+}
+
+// forget removes the sources that have been silent for a further TTL after they
+// stopped being live.  Expected sources are never forgotten.
+func (qp *quorumPolicy) forget(now time.Time) {
+	if qp.expected {
+		return
+	}
+
+	kept := qp.sources[:0]
+	for _, qs := range qp.sources {
+		if now.Before(qs.until.Add(qs.ttl)) {
+			kept = append(kept, qs)
+		}
+	}
+
+	clear(qp.sources[len(kept):])
+	qp.sources = kept
+}
+
+func (qp *quorumPolicy) deadline() time.Time {
+	return qp.next
+}
+
+func (qp *quorumPolicy) misses() int {
+	return qp.missCount
+}
+
+func (qp *quorumPolicy) expire(now time.Time) (v verdict) {
+	for !now.Before(qp.next) && !v.trigger {
+		check := qp.next
+		qp.next = qp.next.Add(qp.ttl)
+		if qp.live(check) >= qp.quorum {
+			qp.missCount = 0
+			continue
+		}
+
+		qp.missCount++
+		qp.logger.Printf("missed quorum %s [misses=%d]", qp.status(check), qp.missCount)
+		v.trigger = qp.missCount >= qp.maxMisses
+	}
+
+	v.misses = qp.missCount
+	if v.trigger {
+		var silent []string
+		for _, qs := range qp.sources {
+			if !qs.live(now) {
+				silent = append(silent, qs.name)
+			}
+		}
+
+		v.details = Details{
+			QuorumDetail: strconv.Itoa(qp.quorum),
+			LiveDetail:   strconv.Itoa(qp.live(now)),
+		}
+
+		if len(silent) > 0 {
+			v.details[SilentDetail] = strings.Join(silent, ",")
+		}
+	}
+
+	return
+}
@@ -0,0 +1,442 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type PolicySuite struct {
+	DMSSuite
+}
+
+func (suite *PolicySuite) TestConsecutive() {
+	for _, maxMisses := range []int{0, 1, 3} {
+		suite.Run(fmt.Sprintf("maxMisses=%d", maxMisses), func() {
+			var (
+				ttl = 10 * time.Second
+				cp  = &consecutivePolicy{
+					logger:    suite.logger,
+					ttl:       ttl,
+					maxMisses: maxMisses,
+				}
+
+				now = suite.now
+			)
+
+			cp.start(now)
+			suite.Equal(now.Add(ttl), cp.deadline())
+
+			// a postpone from any source resets everything
+			now = now.Add(ttl / 2)
+			cp.postpone(now, PostponeRequest{Source: "anything"})
+			suite.Equal(now.Add(ttl), cp.deadline())
+
+			// an early expire does nothing
+			v := cp.expire(now)
+			suite.Zero(v.misses)
+			suite.False(v.trigger)
+
+			expectedTrigger := maxMisses
+			if expectedTrigger < 1 {
+				expectedTrigger = 1
+			}
+
+			for i := 1; i < expectedTrigger; i++ {
+				now = cp.deadline()
+				v = cp.expire(now)
+				suite.Equal(i, v.misses)
+				suite.False(v.trigger)
+			}
+
+			v = cp.expire(cp.deadline())
+			suite.Equal(expectedTrigger, v.misses)
+			suite.True(v.trigger)
+			suite.Empty(v.details)
+		})
+	}
+}
+
+func (suite *PolicySuite) TestConsecutiveCatchUp() {
+	var (
+		ttl = 10 * time.Second
+		cp  = &consecutivePolicy{
+			logger:    suite.logger,
+			ttl:       ttl,
+			maxMisses: 3,
+		}
+	)
+
+	// several intervals elapsing at once are all counted
+	cp.start(suite.now)
+	v := cp.expire(suite.now.Add(2*ttl + ttl/2))
+	suite.Equal(2, v.misses)
+	suite.False(v.trigger)
+	suite.Equal(suite.now.Add(3*ttl), cp.deadline())
+}
+
+func (suite *PolicySuite) TestConsecutiveTTL() {
+	var (
+		ttl = 10 * time.Second
+		cp  = &consecutivePolicy{
+			logger:    suite.logger,
+			ttl:       ttl,
+			maxMisses: 1,
+		}
+	)
+
+	// a requested TTL applies to the next deadline only
+	cp.start(suite.now)
+	cp.postpone(suite.now, PostponeRequest{TTL: time.Minute})
+	suite.Equal(suite.now.Add(time.Minute), cp.deadline())
+
+	v := cp.expire(suite.now.Add(time.Minute))
+	suite.True(v.trigger)
+	suite.Equal(suite.now.Add(time.Minute+ttl), cp.deadline())
+}
+
+func (suite *PolicySuite) TestBudget() {
+	var (
+		ttl = 10 * time.Second
+		bp  = newBudgetPolicy(suite.logger, ttl, 3, 5)
+		now = suite.now
+	)
+
+	bp.start(now)
+	suite.Equal(now.Add(ttl), bp.deadline())
+
+	// a sender that misses every other interval never misses twice in a row,
+	// but eventually exhausts the budget
+	var v verdict
+	for i := 0; i < 2; i++ {
+		now = bp.deadline()
+		v = bp.expire(now)
+		suite.Equal(1, v.misses)
+		suite.False(v.trigger)
+
+		bp.postpone(now.Add(ttl/2), PostponeRequest{Source: "flaky"})
+		suite.Zero(bp.misses())
+		suite.Equal(now.Add(ttl), bp.deadline(), "a postpone does not move the deadline")
+
+		now = bp.deadline()
+		v = bp.expire(now)
+		suite.Zero(v.misses)
+		suite.False(v.trigger)
+	}
+
+	v = bp.expire(bp.deadline())
+	suite.Equal(1, v.misses)
+	suite.True(v.trigger)
+	suite.Equal(Details{PostponesDetail: "2", IntervalsDetail: "5"}, v.details)
+
+	// restarting forgets the window
+	bp.start(now)
+	suite.Equal(5, bp.kept())
+}
+
+func (suite *PolicySuite) TestBudgetSlides() {
+	var (
+		ttl = 10 * time.Second
+		bp  = newBudgetPolicy(suite.logger, ttl, 3, 5)
+		now = suite.now
+	)
+
+	// two misses are within budget, and slide out of the window as intervals are kept
+	bp.start(now)
+	v := bp.expire(now.Add(2 * ttl))
+	suite.Equal(2, v.misses)
+	suite.False(v.trigger)
+	suite.Equal(3, bp.kept())
+
+	for i := 0; i < 5; i++ {
+		now = bp.deadline()
+		bp.postpone(now.Add(-ttl/2), PostponeRequest{})
+		v = bp.expire(now)
+		suite.False(v.trigger)
+	}
+
+	suite.Equal(5, bp.kept())
+
+	// several intervals elapsing at once stop at the trigger
+	v = bp.expire(bp.deadline().Add(10 * ttl))
+	suite.Equal(3, v.misses)
+	suite.True(v.trigger)
+	suite.Equal(2, bp.kept())
+}
+
+func (suite *PolicySuite) TestBudgetTTL() {
+	var (
+		ttl = 10 * time.Second
+		bp  = newBudgetPolicy(suite.logger, ttl, 2, 2)
+	)
+
+	// a requested TTL keeps every interval it covers
+	bp.start(suite.now)
+	bp.postpone(suite.now, PostponeRequest{TTL: 3*ttl + ttl/2})
+	v := bp.expire(suite.now.Add(3 * ttl))
+	suite.Zero(v.misses)
+	suite.False(v.trigger)
+
+	v = bp.expire(bp.deadline())
+	suite.Equal(1, v.misses)
+	suite.True(v.trigger)
+}
+
+func (suite *PolicySuite) TestBudgetRestore() {
+	bp := newBudgetPolicy(suite.logger, 10*time.Second, 3, 5)
+	bp.restore(suite.now, 2)
+	suite.Equal(suite.now, bp.deadline())
+	suite.Equal(2, bp.misses())
+	suite.Equal(3, bp.kept())
+
+	v := bp.expire(suite.now)
+	suite.Equal(3, v.misses)
+	suite.True(v.trigger)
+}
+
+func (suite *PolicySuite) TestSources() {
+	var (
+		sp = newSourcesPolicy(
+			suite.logger,
+			time.Minute,
+			2,
+			[]SourceConfig{
+				{Name: "fast", TTL: 10 * time.Second, MaxMisses: 1},
+				{Name: "slow"},
+			},
+		)
+
+		now = suite.now
+	)
+
+	suite.Require().Len(sp.sources, 2)
+	suite.Equal(time.Minute, sp.sources[1].TTL)
+	suite.Equal(2, sp.sources[1].MaxMisses)
+
+	sp.start(now)
+	suite.Equal(now.Add(10*time.Second), sp.deadline())
+
+	// postpones from the fast source keep it alive, but do nothing for the slow source
+	for i := 0; i < 6; i++ {
+		now = now.Add(9 * time.Second)
+		sp.postpone(now, PostponeRequest{Source: "fast"})
+		sp.postpone(now, PostponeRequest{Source: "unexpected"})
+
+		v := sp.expire(now)
+		suite.False(v.trigger)
+	}
+
+	// the slow source has now missed once
+	now = suite.now.Add(time.Minute)
+	v := sp.expire(now)
+	suite.Equal(1, v.misses)
+	suite.False(v.trigger)
+
+	sp.postpone(now, PostponeRequest{Source: "slow"})
+	v = sp.expire(now)
+	suite.Zero(v.misses)
+
+	// now let the fast source go silent
+	now = now.Add(10 * time.Second)
+	v = sp.expire(now)
+	suite.True(v.trigger)
+	suite.Equal(Details{SilentDetail: "fast"}, v.details)
+}
+
+func (suite *PolicySuite) TestSourcesTTL() {
+	var (
+		sp = newSourcesPolicy(
+			suite.logger,
+			time.Minute,
+			1,
+			[]SourceConfig{
+				{Name: "a"},
+				{Name: "b"},
+			},
+		)
+	)
+
+	sp.start(suite.now)
+	sp.postpone(suite.now, PostponeRequest{Source: "a", TTL: time.Hour})
+	suite.Equal(suite.now.Add(time.Minute), sp.deadline())
+
+	v := sp.expire(suite.now.Add(time.Minute))
+	suite.True(v.trigger)
+	suite.Equal(Details{SilentDetail: "b"}, v.details)
+}
+
+func (suite *PolicySuite) TestSourcesAllSilent() {
+	var (
+		sp = newSourcesPolicy(
+			suite.logger,
+			time.Minute,
+			0,
+			[]SourceConfig{
+				{Name: "a"},
+				{Name: "b"},
+			},
+		)
+	)
+
+	sp.start(suite.now)
+	v := sp.expire(suite.now.Add(time.Minute))
+	suite.True(v.trigger)
+	suite.Equal(1, v.misses)
+	suite.Equal(Details{SilentDetail: "a,b"}, v.details)
+}
+
+func (suite *PolicySuite) TestQuorum() {
+	var (
+		ttl = time.Minute
+		qp  = newQuorumPolicy(suite.logger, ttl, 2, 2, nil)
+		now = suite.now
+	)
+
+	qp.start(now)
+	suite.Equal(now.Add(ttl), qp.deadline())
+
+	// two of three replicas keep the quorum
+	for i := 0; i < 3; i++ {
+		now = now.Add(ttl / 2)
+		qp.postpone(now, PostponeRequest{Source: "replica-1"})
+		qp.postpone(now, PostponeRequest{Source: "replica-2"})
+		suite.Equal(2, qp.live(now))
+
+		v := qp.expire(now)
+		suite.Zero(v.misses)
+		suite.False(v.trigger)
+	}
+
+	// a single replica falls short, but misses are allowed
+	qp.postpone(now.Add(10*time.Second), PostponeRequest{Source: "replica-3"})
+	v := qp.expire(suite.now.Add(3 * ttl))
+	suite.Equal(1, v.misses)
+	suite.False(v.trigger)
+
+	v = qp.expire(qp.deadline())
+	suite.Equal(2, v.misses)
+	suite.True(v.trigger)
+	suite.Equal("2", v.details[QuorumDetail])
+	suite.Equal("0", v.details[LiveDetail])
+	suite.Equal("replica-1,replica-2,replica-3", v.details[SilentDetail])
+}
+
+func (suite *PolicySuite) TestQuorumRecovery() {
+	var (
+		ttl = time.Minute
+		qp  = newQuorumPolicy(suite.logger, ttl, 3, 1, nil)
+	)
+
+	qp.start(suite.now)
+	v := qp.expire(suite.now.Add(2 * ttl))
+	suite.Equal(2, v.misses)
+
+	// reaching the quorum resets the misses
+	qp.postpone(suite.now.Add(2*ttl+ttl/2), PostponeRequest{Source: "any"})
+	v = qp.expire(suite.now.Add(3 * ttl))
+	suite.Zero(v.misses)
+	suite.False(v.trigger)
+}
+
+func (suite *PolicySuite) TestQuorumWeights() {
+	var (
+		ttl = time.Minute
+		qp  = newQuorumPolicy(
+			suite.logger,
+			ttl,
+			0,
+			3,
+			[]SourceConfig{
+				{Name: "primary", Weight: 2},
+				{Name: "secondary"},
+				{Name: "tertiary", TTL: 10 * time.Second},
+			},
+		)
+
+		now = suite.now
+	)
+
+	qp.start(now)
+	qp.postpone(now, PostponeRequest{Source: "tertiary"})
+	qp.postpone(now, PostponeRequest{Source: "unexpected"})
+	suite.Equal(1, qp.live(now))
+
+	now = now.Add(5 * time.Second)
+	qp.postpone(now, PostponeRequest{Source: "primary"})
+	suite.Equal(3, qp.live(now))
+
+	// the tertiary source has a shorter TTL, so the quorum is lost at the check
+	v := qp.expire(suite.now.Add(ttl))
+	suite.True(v.trigger)
+	suite.Equal("2", v.details[LiveDetail])
+	suite.Equal("secondary,tertiary", v.details[SilentDetail])
+}
+
+func (suite *PolicySuite) TestQuorumTTL() {
+	var (
+		ttl = time.Minute
+		qp  = newQuorumPolicy(suite.logger, ttl, 1, 1, nil)
+	)
+
+	// a requested TTL keeps a source live for longer than the switch's TTL
+	qp.start(suite.now)
+	qp.postpone(suite.now, PostponeRequest{Source: "slow", TTL: 5 * ttl})
+	for i := 1; i < 5; i++ {
+		v := qp.expire(suite.now.Add(time.Duration(i) * ttl))
+		suite.False(v.trigger)
+	}
+
+	v := qp.expire(suite.now.Add(5 * ttl))
+	suite.True(v.trigger)
+}
+
+func (suite *PolicySuite) TestQuorumForget() {
+	var (
+		ttl = time.Minute
+		qp  = newQuorumPolicy(suite.logger, ttl, 1, 2, nil)
+		now = suite.now
+	)
+
+	qp.start(now)
+	qp.postpone(now, PostponeRequest{Source: "old"})
+	qp.postpone(now.Add(ttl), PostponeRequest{Source: "recent"})
+	suite.Len(qp.sources, 2)
+
+	// a source silent for a further TTL after it stopped being live is forgotten
+	now = now.Add(2*ttl + time.Second)
+	qp.postpone(now, PostponeRequest{Source: "new"})
+	suite.Len(qp.sources, 2)
+	suite.Equal("recent", qp.sources[0].name)
+	suite.Equal("new", qp.sources[1].name)
+}
+
+func (suite *PolicySuite) TestQuorumMaxSources() {
+	var (
+		ttl = time.Minute
+		qp  = newQuorumPolicy(suite.logger, ttl, 1, MaxQuorumSources+1, nil)
+	)
+
+	qp.start(suite.now)
+	for i := range MaxQuorumSources + 1 {
+		qp.postpone(suite.now, PostponeRequest{Source: strconv.Itoa(i)})
+	}
+
+	// the extra source is ignored, so the quorum cannot be reached
+	suite.Len(qp.sources, MaxQuorumSources)
+	suite.Equal(MaxQuorumSources, qp.live(suite.now))
+
+	// once the sources have been forgotten, new sources are tracked again
+	later := suite.now.Add(2 * ttl)
+	qp.postpone(later, PostponeRequest{Source: "later"})
+	suite.Len(qp.sources, 1)
+}
+
+func TestPolicy(t *testing.T) {
+	suite.Run(t, new(PolicySuite))
+}
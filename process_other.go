@@ -0,0 +1,30 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !unix
+
+package main
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup does nothing on platforms without process groups.
+func setProcessGroup(*exec.Cmd) {}
+
+// terminateProcess kills a started command, since there is no gentler signal
+// on platforms without process groups.
+func terminateProcess(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// groupRunning returns false, since there is no process group that could
+// outlive the command.
+func groupRunning(*exec.Cmd) bool {
+	return false
+}
+
+// killProcess kills a started command.
+func killProcess(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
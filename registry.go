@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/xmidt-org/chronon"
+	"go.uber.org/fx"
+)
+
+var (
+	// ErrDuplicateSwitch is returned by Registry.Register if a switch with the
+	// same name has already been registered.
+	ErrDuplicateSwitch = errors.New("A switch with that name is already registered")
+
+	// ErrInvalidSwitchName is returned by Registry.Register if a switch name has
+	// characters other than letters, digits, '.', '_', and '-'.
+	ErrInvalidSwitchName = errors.New("A switch name may only contain letters, digits, '.', '_', and '-'")
+
+	// switchName matches the names that may be registered.  A name appears in the
+	// switch's URIs and log output, so it is restricted to characters that need
+	// no escaping in either.
+	switchName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+)
+
+// Registry holds the named Switches hosted by a single dms process.  Each
+// switch in a registry is activated and deactivated independently, and
+// triggering one switch has no effect on the others.
+type Registry struct {
+	lock     sync.RWMutex
+	switches map[string]*Switch
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		switches: make(map[string]*Switch),
+	}
+}
+
+// Register adds a named switch to this registry.  If a switch with the given
+// name is already present, ErrDuplicateSwitch is returned.  A name with any
+// character other than those allowed results in ErrInvalidSwitchName.
+func (r *Registry) Register(name string, s *Switch) error {
+	if !switchName.MatchString(name) {
+		return ErrInvalidSwitchName
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, exists := r.switches[name]; exists {
+		return ErrDuplicateSwitch
+	}
+
+	r.switches[name] = s
+	return nil
+}
+
+// Get returns the switch with the given name, if it exists.
+func (r *Registry) Get(name string) (s *Switch, ok bool) {
+	r.lock.RLock()
+	s, ok = r.switches[name]
+	r.lock.RUnlock()
+	return
+}
+
+// Names returns the sorted names of all switches in this registry.
+func (r *Registry) Names() []string {
+	r.lock.RLock()
+	names := make([]string, 0, len(r.switches))
+	for name := range r.switches {
+		names = append(names, name)
+	}
+
+	r.lock.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// Activate starts each switch in this registry in its own goroutine.
+func (r *Registry) Activate() {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, s := range r.switches {
+		go s.Activate()
+	}
+}
+
+// Deactivate deactivates every switch in this registry.  Switches that have
+// already triggered or been deactivated are ignored.
+func (r *Registry) Deactivate() {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, s := range r.switches {
+		s.Deactivate()
+	}
+}
+
+// Abort stops the actions that any switch in this registry is running.
+func (r *Registry) Abort() {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, s := range r.switches {
+		s.Abort()
+	}
+}
+
+// RegistryIn describes the dependencies necessary for creating a Registry.
+type RegistryIn struct {
+	fx.In
+
+	Logger      Logger
+	CommandLine CommandLine      `optional:"true"`
+	Listeners   []SwitchListener `group:"listeners"`
+	Clock       chronon.Clock    `optional:"true"`
+}
+
+// NewRegistryFromConfig creates a Registry holding a Switch for each
+// SwitchSpec in the given Config.  The Logger, Clock, Listeners, and DryRun
+// of the base SwitchConfig are shared by every switch.
+func NewRegistryFromConfig(base SwitchConfig, cfg Config) (*Registry, error) {
+	r := NewRegistry()
+	for _, spec := range cfg.Switches {
+		sc, err := spec.switchConfig(base.Logger, base.Clock)
+		if err == nil {
+			sc.Listeners = base.Listeners
+			sc.DryRun = base.DryRun
+			err = r.Register(spec.Name, NewSwitch(sc))
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// provideRegistry creates an fx.Option that bootstraps a *Registry from the
+// --config file, if one was supplied, and binds it to the fx.App lifecycle.
+func provideRegistry() fx.Option {
+	return fx.Options(
+		fx.Provide(
+			func(in RegistryIn) (*Registry, error) {
+				var cfg Config
+				if len(in.CommandLine.Config) > 0 {
+					var err error
+					if cfg, err = LoadConfig(in.CommandLine.Config); err != nil {
+						return nil, err
+					}
+				}
+
+				return NewRegistryFromConfig(
+					SwitchConfig{
+						Logger:    in.Logger,
+						Clock:     in.Clock,
+						Listeners: in.Listeners,
+						DryRun:    in.CommandLine.DryRun,
+					},
+					cfg,
+				)
+			},
+		),
+		fx.Invoke(
+			func(l fx.Lifecycle, r *Registry) {
+				l.Append(fx.Hook{
+					OnStart: func(context.Context) error {
+						r.Activate()
+						return nil
+					},
+					OnStop: func(context.Context) error {
+						r.Abort()
+						r.Deactivate()
+						return nil
+					},
+				})
+			},
+		),
+	)
+}
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/xmidt-org/chronon"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+)
+
+type RegistrySuite struct {
+	DMSSuite
+}
+
+func (suite *RegistrySuite) TestRegister() {
+	var (
+		r     = NewRegistry()
+		cfg   = SwitchConfig{Logger: suite.logger}
+		first = suite.newSwitch(cfg)
+	)
+
+	suite.NoError(r.Register("first", first))
+	suite.ErrorIs(r.Register("first", suite.newSwitch(cfg)), ErrDuplicateSwitch)
+	suite.NoError(r.Register("another", suite.newSwitch(cfg)))
+
+	for _, name := range []string{"", "50%d", "a/b", "a b"} {
+		suite.ErrorIs(r.Register(name, suite.newSwitch(cfg)), ErrInvalidSwitchName, name)
+	}
+
+	s, ok := r.Get("first")
+	suite.True(ok)
+	suite.Same(first, s)
+
+	_, ok = r.Get("nosuch")
+	suite.False(ok)
+
+	suite.Equal([]string{"another", "first"}, r.Names())
+}
+
+func (suite *RegistrySuite) TestIndependentTrigger() {
+	var (
+		triggered   = newMockActions(1)
+		untriggered = newMockActions(1)
+
+		triggeredCfg, triggeredClock = suite.switchConfig(time.Minute, 0, triggered.actions()...)
+		untriggeredCfg, _            = suite.switchConfig(time.Hour, 0, untriggered.actions()...)
+
+		triggeredSwitch   = suite.newSwitch(triggeredCfg)
+		untriggeredSwitch = suite.newSwitch(untriggeredCfg)
+
+		r        = NewRegistry()
+		onTicker = make(chan chronon.FakeTicker, 1)
+	)
+
+	suite.Require().NoError(r.Register("triggered", triggeredSwitch))
+	suite.Require().NoError(r.Register("untriggered", untriggeredSwitch))
+
+	triggeredClock.NotifyOnTicker(onTicker)
+	calls := triggered.expectRunOnce(errors.New("expected"))
+	r.Activate()
+
+	ft := <-onTicker
+	triggeredClock.Set(ft.When())
+	triggered.waitForCalls(suite.T(), time.Second, calls)
+
+	// the other switch is still running
+	suite.Eventually(
+		func() bool { return untriggeredSwitch.Postpone(PostponeRequest{Source: "test"}) },
+		time.Second,
+		10*time.Millisecond,
+	)
+
+	suite.False(triggeredSwitch.Postpone(PostponeRequest{Source: "test"}))
+
+	r.Deactivate()
+	suite.False(untriggeredSwitch.Postpone(PostponeRequest{Source: "test"}))
+
+	triggered.assertExpectations(suite.T())
+	untriggered.assertExpectations(suite.T())
+}
+
+func (suite *RegistrySuite) TestProvideRegistry() {
+	suite.Run("NoConfig", func() {
+		var (
+			r   *Registry
+			app = fxtest.New(
+				suite.T(),
+				fx.Logger(DiscardLogger{}),
+				suite.provideLogger(),
+				provideRegistry(),
+				fx.Populate(&r),
+			)
+		)
+
+		app.RequireStart()
+		suite.Require().NotNil(r)
+		suite.Empty(r.Names())
+		app.RequireStop()
+	})
+
+	suite.Run("Config", func() {
+		path := filepath.Join(suite.T().TempDir(), "dms.json")
+		suite.Require().NoError(os.WriteFile(
+			path,
+			[]byte(`{"switches": [{"name": "one", "exec": ["echo one"], "ttl": "1h"}, {"name": "two", "exec": ["echo two"], "ttl": "1h"}]}`),
+			0600,
+		))
+
+		var (
+			r   *Registry
+			app = fxtest.New(
+				suite.T(),
+				fx.Logger(DiscardLogger{}),
+				suite.provideLogger(),
+				fx.Supply(CommandLine{Config: path}),
+				provideRegistry(),
+				fx.Populate(&r),
+			)
+		)
+
+		app.RequireStart()
+		suite.Require().NotNil(r)
+		suite.Equal([]string{"one", "two"}, r.Names())
+		app.RequireStop()
+	})
+
+	suite.Run("DuplicateName", func() {
+		path := filepath.Join(suite.T().TempDir(), "dms.json")
+		suite.Require().NoError(os.WriteFile(
+			path,
+			[]byte(`{"switches": [{"name": "one", "exec": ["echo one"]}, {"name": "one", "exec": ["echo two"]}]}`),
+			0600,
+		))
+
+		app := fx.New(
+			fx.Logger(DiscardLogger{}),
+			suite.provideLogger(),
+			fx.Supply(CommandLine{Config: path}),
+			provideRegistry(),
+			fx.Invoke(func(*Registry) {}),
+		)
+
+		suite.ErrorIs(app.Err(), ErrDuplicateSwitch)
+	})
+
+	suite.Run("InvalidName", func() {
+		path := filepath.Join(suite.T().TempDir(), "dms.json")
+		suite.Require().NoError(os.WriteFile(
+			path,
+			[]byte(`{"switches": [{"name": "50%", "exec": ["echo one"]}]}`),
+			0600,
+		))
+
+		app := fx.New(
+			fx.Logger(DiscardLogger{}),
+			suite.provideLogger(),
+			fx.Supply(CommandLine{Config: path}),
+			provideRegistry(),
+			fx.Invoke(func(*Registry) {}),
+		)
+
+		suite.ErrorIs(app.Err(), ErrInvalidSwitchName)
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	suite.Run(t, new(RegistrySuite))
+}
@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xmidt-org/chronon"
+)
+
+const (
+	// FailureContinue is the default failure policy, in which the remaining actions
+	// of a trigger run after an action has failed.
+	FailureContinue = "continue"
+
+	// FailureStopRemaining is the failure policy in which the remaining actions of
+	// a trigger are skipped after an action has failed.  Actions which must always
+	// run, such as a ShutdownerAction, still run, so dms exits as usual.
+	FailureStopRemaining = "stop-remaining"
+
+	// FailureAbortAndKeepRunning is the failure policy in which all of the remaining
+	// actions of a trigger are skipped after an action has failed, including any
+	// ShutdownerAction, so that dms keeps running.
+	FailureAbortAndKeepRunning = "abort-and-keep-running"
+
+	// DefaultRetryBackoff is the delay before the first retry of an action when no
+	// backoff is supplied or when the backoff is nonpositive.
+	DefaultRetryBackoff time.Duration = 1 * time.Second
+
+	// DefaultRetryMaxBackoff is the maximum delay between retries of an action when
+	// no maximum is supplied or when the maximum is nonpositive.
+	DefaultRetryMaxBackoff time.Duration = 1 * time.Minute
+
+	// MaxRetryWait is the longest total time that a single action of a trigger
+	// waits between its retries.  A switch's loop is busy while its actions run,
+	// so postpones and other requests wait for the trigger to finish.  Once this
+	// limit would be exceeded, a failed action is not retried again.
+	MaxRetryWait time.Duration = 10 * time.Minute
+)
+
+var (
+	// ErrInvalidOnFailure indicates a failure policy other than FailureContinue,
+	// FailureStopRemaining, or FailureAbortAndKeepRunning.
+	ErrInvalidOnFailure = errors.New("The failure policy must be continue, stop-remaining, or abort-and-keep-running")
+
+	// ErrTriggerStopped is the result of an action that was skipped because an
+	// earlier action with the FailureStopRemaining policy failed.
+	ErrTriggerStopped = errors.New("The trigger stopped after an earlier action failed")
+
+	// ErrTriggerAborted is the result of an action that was skipped because an
+	// earlier action with the FailureAbortAndKeepRunning policy failed.
+	ErrTriggerAborted = errors.New("The trigger was aborted after an earlier action failed")
+)
+
+// RetryPolicy describes how an action is retried when it fails, and what a trigger
+// does once the action's retries are exhausted.  The zero value runs an action
+// once, and continues with the remaining actions whether or not it fails.
+type RetryPolicy struct {
+	// Retries is the number of times a failed action is retried.  If nonpositive,
+	// a failed action is not retried.
+	Retries int
+
+	// Backoff is the delay before the first retry.  Each further retry doubles
+	// the delay, up to MaxBackoff.
+	//
+	// If nonpositive, DefaultRetryBackoff is used.
+	Backoff time.Duration
+
+	// MaxBackoff is the maximum delay between retries.
+	//
+	// If nonpositive, DefaultRetryMaxBackoff is used.
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction, from 0 to 1, by which each delay is randomly
+	// lengthened or shortened.  Jitter keeps many instances of dms that failed
+	// together from retrying in lockstep.
+	Jitter float64
+
+	// OnFailure is the failure policy, which decides what a trigger does after
+	// this action has failed and exhausted its retries.  If unset, FailureContinue
+	// is used.
+	OnFailure string
+}
+
+// delay returns the time to wait before the given retry, where the first retry is 1.
+func (rp RetryPolicy) delay(retry int) time.Duration {
+	backoff, maxBackoff := rp.Backoff, rp.MaxBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultRetryMaxBackoff
+	}
+
+	d := min(backoff, maxBackoff)
+	for i := 1; i < retry && d < maxBackoff; i++ {
+		d = min(2*d, maxBackoff)
+	}
+
+	if jitter := min(rp.Jitter, 1.0); jitter > 0 {
+		d += time.Duration((2*rand.Float64() - 1) * jitter * float64(d))
+	}
+
+	return d
+}
+
+// validOnFailure returns true if the given failure policy is known.  An empty
+// policy is the same as FailureContinue.
+func validOnFailure(onFailure string) bool {
+	switch onFailure {
+	case "", FailureContinue, FailureStopRemaining, FailureAbortAndKeepRunning:
+		return true
+
+	default:
+		return false
+	}
+}
+
+// apply returns a copy of this policy with the given settings, of the form
+// key:value[,key:value...], e.g. retries:3,on-failure:stop-remaining.  The keys
+// are retries, retry-backoff, retry-max-backoff, retry-jitter, and on-failure,
+// each of which overrides the corresponding field.
+func (rp RetryPolicy) apply(settings string) (RetryPolicy, error) {
+	for _, setting := range strings.Split(settings, ",") {
+		key, value, ok := strings.Cut(setting, ":")
+		if !ok {
+			return RetryPolicy{}, ErrInvalidExecPolicy
+		}
+
+		var err error
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "retries":
+			rp.Retries, err = strconv.Atoi(value)
+
+		case "retry-backoff":
+			rp.Backoff, err = time.ParseDuration(value)
+
+		case "retry-max-backoff":
+			rp.MaxBackoff, err = time.ParseDuration(value)
+
+		case "retry-jitter":
+			rp.Jitter, err = strconv.ParseFloat(value, 64)
+
+		case "on-failure":
+			rp.OnFailure = value
+			if !validOnFailure(value) {
+				err = ErrInvalidOnFailure
+			}
+
+		default:
+			return RetryPolicy{}, ErrInvalidExecPolicy
+		}
+
+		if err != nil {
+			return RetryPolicy{}, errors.Join(ErrInvalidExecPolicy, err)
+		}
+	}
+
+	return rp, nil
+}
+
+// RetryingAction is an optional interface for Actions that have a RetryPolicy.
+// An Action that does not implement this interface is run once, and the trigger
+// continues whether or not it fails.
+type RetryingAction interface {
+	Action
+	RetryPolicy() RetryPolicy
+}
+
+// retryPolicy returns the RetryPolicy of the given action.
+func retryPolicy(a Action) RetryPolicy {
+	if ra, ok := a.(RetryingAction); ok {
+		return ra.RetryPolicy()
+	}
+
+	return RetryPolicy{}
+}
+
+// retryAction runs an action, retrying it according to the given policy for
+// as long as it fails.  The delays between retries are timed by the given clock,
+// and add up to no more than MaxRetryWait.  No retries are made once the context
+// is done.
+func retryAction(ctx context.Context, l Logger, c chronon.Clock, a Action, d Details, rp RetryPolicy) error {
+	var waited time.Duration
+	err := runAction(ctx, a, d)
+	for retry := 1; err != nil && retry <= rp.Retries && ctx.Err() == nil; retry++ {
+		delay := rp.delay(retry)
+		if waited += delay; waited > MaxRetryWait {
+			l.Printf("action error: %s [retries stopped after waiting %s]", err, (waited - delay).Round(time.Millisecond))
+			return err
+		}
+
+		l.Printf("action error: %s [retry=%d/%d] [delay=%s]", err, retry, rp.Retries, delay.Round(time.Millisecond))
+
+		timer := c.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+
+		case <-timer.C():
+		}
+
+		err = runAction(ctx, a, d)
+	}
+
+	return err
+}
+
+// skippedError returns true if the given error is the result of an action that
+// was skipped, rather than one that ran and failed.
+func skippedError(err error) bool {
+	return errors.Is(err, ErrTriggerTimeout) || errors.Is(err, ErrTriggerCanceled) ||
+		errors.Is(err, ErrTriggerStopped) || errors.Is(err, ErrTriggerAborted)
+}
@@ -0,0 +1,286 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/xmidt-org/chronon"
+	"go.uber.org/fx"
+)
+
+// retryingAction is a mocked Action with a RetryPolicy
+type retryingAction struct {
+	*mockAction
+	policy RetryPolicy
+}
+
+var _ RetryingAction = retryingAction{}
+
+func (ra retryingAction) RetryPolicy() RetryPolicy {
+	return ra.policy
+}
+
+type RetrySuite struct {
+	DMSSuite
+}
+
+func (suite *RetrySuite) newRetryingAction(rp RetryPolicy) retryingAction {
+	return retryingAction{
+		mockAction: &mockAction{label: "retrying"},
+		policy:     rp,
+	}
+}
+
+func (suite *RetrySuite) TestDelay() {
+	suite.Run("Defaults", func() {
+		var rp RetryPolicy
+		suite.Equal(DefaultRetryBackoff, rp.delay(1))
+		suite.Equal(2*DefaultRetryBackoff, rp.delay(2))
+		suite.Equal(DefaultRetryMaxBackoff, rp.delay(100))
+	})
+
+	suite.Run("Backoff", func() {
+		rp := RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+		suite.Equal(time.Second, rp.delay(1))
+		suite.Equal(2*time.Second, rp.delay(2))
+		suite.Equal(4*time.Second, rp.delay(3))
+		suite.Equal(5*time.Second, rp.delay(4))
+		suite.Equal(5*time.Second, rp.delay(10))
+
+		// the maximum applies to the first retry, too
+		rp.Backoff = time.Minute
+		suite.Equal(5*time.Second, rp.delay(1))
+	})
+
+	suite.Run("Jitter", func() {
+		rp := RetryPolicy{Backoff: time.Second, Jitter: 0.5}
+		for range 100 {
+			d := rp.delay(1)
+			suite.GreaterOrEqual(d, 500*time.Millisecond)
+			suite.LessOrEqual(d, 1500*time.Millisecond)
+		}
+
+		// jitter is capped at 1
+		rp.Jitter = 5
+		for range 100 {
+			d := rp.delay(1)
+			suite.GreaterOrEqual(d, time.Duration(0))
+			suite.LessOrEqual(d, 2*time.Second)
+		}
+	})
+}
+
+func (suite *RetrySuite) TestRetryAction() {
+	expectedErr := errors.New("expected")
+
+	suite.Run("Succeeds", func() {
+		var (
+			output bytes.Buffer
+			ra     = suite.newRetryingAction(RetryPolicy{Retries: 3, Backoff: time.Millisecond})
+		)
+
+		ra.ExpectRun().Return(expectedErr).Twice()
+		ra.ExpectRun().Return(nil).Once()
+		suite.NoError(retryAction(context.Background(), WriterLogger{Writer: &output}, chronon.SystemClock(), ra, nil, ra.policy))
+		suite.Contains(output.String(), "action error: expected [retry=1/3] [delay=1ms]")
+		suite.Contains(output.String(), "action error: expected [retry=2/3] [delay=2ms]")
+		ra.AssertExpectations(suite.T())
+	})
+
+	suite.Run("Exhausted", func() {
+		ra := suite.newRetryingAction(RetryPolicy{Retries: 2, Backoff: time.Millisecond})
+		ra.ExpectRun().Return(expectedErr).Times(3)
+		suite.ErrorIs(retryAction(context.Background(), suite.logger, chronon.SystemClock(), ra, nil, ra.policy), expectedErr)
+		ra.AssertExpectations(suite.T())
+	})
+
+	suite.Run("Canceled", func() {
+		var (
+			ra          = suite.newRetryingAction(RetryPolicy{Retries: 2, Backoff: time.Hour})
+			ctx, cancel = context.WithCancel(context.Background())
+			start       = time.Now()
+		)
+
+		// the backoff is cut short, and no more retries are made
+		ra.ExpectRun().Return(expectedErr).Once()
+		time.AfterFunc(50*time.Millisecond, cancel)
+		suite.ErrorIs(retryAction(ctx, suite.logger, chronon.SystemClock(), ra, nil, ra.policy), expectedErr)
+		suite.Less(time.Since(start), 5*time.Second)
+		ra.AssertExpectations(suite.T())
+	})
+
+	suite.Run("Clock", func() {
+		var (
+			ra      = suite.newRetryingAction(RetryPolicy{Retries: 1, Backoff: 5 * time.Minute, MaxBackoff: 5 * time.Minute})
+			clock   = chronon.NewFakeClock(time.Now())
+			onTimer = make(chan chronon.FakeTimer, 1)
+			result  = make(chan error, 1)
+		)
+
+		ra.ExpectRun().Return(expectedErr).Once()
+		ra.ExpectRun().Return(nil).Once()
+		clock.NotifyOnTimer(onTimer)
+		go func() {
+			result <- retryAction(context.Background(), suite.logger, clock, ra, nil, ra.policy)
+		}()
+
+		// the backoff waits on the given clock
+		select {
+		case <-onTimer:
+		case <-time.After(5 * time.Second):
+			suite.FailNow("no retry timer was created")
+		}
+
+		clock.Add(5 * time.Minute)
+		suite.NoError(<-result)
+		ra.AssertExpectations(suite.T())
+	})
+
+	suite.Run("MaxRetryWait", func() {
+		var (
+			output  bytes.Buffer
+			ra      = suite.newRetryingAction(RetryPolicy{Retries: 3, Backoff: MaxRetryWait, MaxBackoff: MaxRetryWait})
+			clock   = chronon.NewFakeClock(time.Now())
+			onTimer = make(chan chronon.FakeTimer, 1)
+			result  = make(chan error, 1)
+		)
+
+		ra.ExpectRun().Return(expectedErr).Twice()
+		clock.NotifyOnTimer(onTimer)
+		go func() {
+			result <- retryAction(context.Background(), WriterLogger{Writer: &output}, clock, ra, nil, ra.policy)
+		}()
+
+		select {
+		case <-onTimer:
+		case <-time.After(5 * time.Second):
+			suite.FailNow("no retry timer was created")
+		}
+
+		// a second wait would exceed the limit, so no more retries are made
+		clock.Add(MaxRetryWait)
+		suite.ErrorIs(<-result, expectedErr)
+		suite.Contains(output.String(), "[retries stopped after waiting 10m0s]")
+		ra.AssertExpectations(suite.T())
+	})
+}
+
+func (suite *RetrySuite) TestOnFailure() {
+	testData := []struct {
+		onFailure string
+		next      error
+		shutdown  error
+	}{
+		{"", nil, nil},
+		{FailureContinue, nil, nil},
+		{FailureStopRemaining, ErrTriggerStopped, nil},
+		{FailureAbortAndKeepRunning, ErrTriggerAborted, ErrTriggerAborted},
+	}
+
+	for _, testCase := range testData {
+		suite.Run(testCase.onFailure, func() {
+			var (
+				failing    = suite.newRetryingAction(RetryPolicy{Retries: 1, Backoff: time.Millisecond, OnFailure: testCase.onFailure})
+				next       = &ExecAction{Path: "true", Args: []string{"true"}}
+				shutdowner = new(mockShutdowner)
+				results    []error
+				onResult   = func(_ Action, err error) { results = append(results, err) }
+			)
+
+			failing.ExpectRun().Return(errors.New("expected")).Twice()
+			if testCase.shutdown == nil {
+				shutdowner.On("Shutdown", []fx.ShutdownOption(nil)).Return(error(nil)).Once()
+			}
+
+			runSchedule(context.Background(), suite.logger, nil, Schedule{}, onResult, failing, next, ShutdownerAction{Shutdowner: shutdowner})
+			suite.Require().Len(results, 3)
+			suite.Error(results[0])
+			suite.Equal(testCase.next, results[1])
+			suite.Equal(testCase.shutdown, results[2])
+
+			failing.AssertExpectations(suite.T())
+			shutdowner.AssertExpectations(suite.T())
+		})
+	}
+}
+
+func (suite *RetrySuite) TestParseExec() {
+	actions, err := ParseExec(CommandLine{
+		Exec:            []string{"echo test"},
+		Retries:         2,
+		RetryBackoff:    time.Second,
+		RetryMaxBackoff: time.Minute,
+		RetryJitter:     0.2,
+		OnFailure:       FailureStopRemaining,
+	})
+
+	suite.Require().NoError(err)
+	suite.Require().Len(actions, 1)
+	suite.Equal(
+		RetryPolicy{Retries: 2, Backoff: time.Second, MaxBackoff: time.Minute, Jitter: 0.2, OnFailure: FailureStopRemaining},
+		retryPolicy(actions[0]),
+	)
+
+	// actions without a policy are run once
+	suite.Equal(RetryPolicy{}, retryPolicy(ShutdownerAction{}))
+}
+
+func (suite *RetrySuite) TestParseExecPolicy() {
+	suite.Run("Valid", func() {
+		actions, err := ParseExec(CommandLine{
+			Exec:      []string{"echo one", "echo two"},
+			Retries:   1,
+			OnFailure: FailureContinue,
+			ExecPolicy: []string{
+				"2=retries:3,retry-backoff:1s,retry-max-backoff:1m,retry-jitter:0.5,on-failure:stop-remaining",
+			},
+		})
+
+		suite.Require().NoError(err)
+		suite.Require().Len(actions, 2)
+		suite.Equal(RetryPolicy{Retries: 1, OnFailure: FailureContinue}, retryPolicy(actions[0]))
+		suite.Equal(
+			RetryPolicy{Retries: 3, Backoff: time.Second, MaxBackoff: time.Minute, Jitter: 0.5, OnFailure: FailureStopRemaining},
+			retryPolicy(actions[1]),
+		)
+	})
+
+	suite.Run("Invalid", func() {
+		testCases := []struct {
+			policy   string
+			expected error
+		}{
+			{policy: "retries:3", expected: ErrInvalidExecPolicy},
+			{policy: "x=retries:3", expected: ErrInvalidExecPolicy},
+			{policy: "0=retries:3", expected: ErrInvalidExecPolicy},
+			{policy: "3=retries:3", expected: ErrInvalidExecPolicy},
+			{policy: "1=retries", expected: ErrInvalidExecPolicy},
+			{policy: "1=nosuch:3", expected: ErrInvalidExecPolicy},
+			{policy: "1=retries:many", expected: ErrInvalidExecPolicy},
+			{policy: "1=retry-backoff:soon", expected: ErrInvalidExecPolicy},
+			{policy: "1=on-failure:nosuch", expected: ErrInvalidOnFailure},
+		}
+
+		for _, testCase := range testCases {
+			suite.Run(testCase.policy, func() {
+				_, err := ParseExec(CommandLine{
+					Exec:       []string{"echo one", "echo two"},
+					ExecPolicy: []string{testCase.policy},
+				})
+
+				suite.ErrorIs(err, testCase.expected)
+			})
+		}
+	})
+}
+
+func TestRetry(t *testing.T) {
+	suite.Run(t, new(RetrySuite))
+}
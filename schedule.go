@@ -0,0 +1,235 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/xmidt-org/chronon"
+)
+
+var (
+	// ErrInvalidAfter is returned by ParseAfter to indicate a malformed --after value.
+	ErrInvalidAfter = errors.New("A dependency must be of the form command=command[,command...], where each command is the position of an --exec, counting from 1, and a command only waits on earlier commands")
+)
+
+// Schedule describes how the actions of a single trigger are run.  The zero
+// value runs actions one at a time, in order.
+type Schedule struct {
+	// Parallel is the maximum number of actions that run at once.  If less
+	// than 2, actions run one at a time.
+	Parallel int
+
+	// After maps the index of an action to the indexes of the earlier actions
+	// which must finish before it starts.  An action waits on an earlier action
+	// whether or not that action succeeded.  Indexes that are out of range are
+	// ignored.
+	After map[int][]int
+
+	// Clock times the delays between retries of an action.  If nil, the system
+	// clock is used.
+	Clock chronon.Clock
+}
+
+// BarrierAction is an optional interface for Actions that must wait for every
+// action before them, even when a schedule runs actions in parallel.  Wrappers
+// such as DryRunAction forward Barrier to the action they wrap.
+type BarrierAction interface {
+	Action
+	Barrier() bool
+}
+
+// barrier returns true if the given action must wait for every action before it,
+// as a ShutdownerAction does.
+func barrier(a Action) bool {
+	ba, ok := a.(BarrierAction)
+	return ok && ba.Barrier()
+}
+
+// ready returns true if the action at index i may start, given which actions
+// have finished.  Without dependencies, one action at a time runs in order,
+// while a parallel schedule lets any action start once its dependencies have
+// finished.
+func (sc Schedule) ready(i int, actions []Action, finished []bool) bool {
+	if sc.Parallel < 2 || barrier(actions[i]) {
+		for j := range i {
+			if !finished[j] {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	for _, j := range sc.After[i] {
+		if j >= 0 && j < i && !finished[j] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// scheduleResult is the outcome of a single action run by runSchedule.
+type scheduleResult struct {
+	i   int
+	err error
+}
+
+// runSchedule runs the given actions according to a schedule, providing a
+// standard output format for each action.  Once the context is done, any
+// ContextAction that is running is stopped and any further actions are skipped.
+// Actions such as a ShutdownerAction, which must always happen, still run.  If
+// onResult is not nil, it is invoked with the result of each action.
+//
+// Each action is retried according to its RetryPolicy.  Once an action has
+// failed and exhausted its retries, its failure policy decides whether actions
+// that have not yet started still run.  Actions which are already running are
+// not affected.
+//
+// Logging and onResult happen on the calling goroutine, in the order in which
+// actions start and finish.  This function returns true if an action with the
+// FailureAbortAndKeepRunning policy failed.
+func runSchedule(ctx context.Context, l Logger, d Details, sc Schedule, onResult func(Action, error), actions ...Action) (aborted bool) {
+	if len(d) > 0 {
+		l.Printf("triggering %s", d)
+	}
+
+	// stopped is canceled once an action with FailureStopRemaining fails, and is
+	// used to start the remaining actions, so that only those which must always
+	// run, such as a ShutdownerAction, do anything
+	stopped, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+
+	clock := sc.Clock
+	if clock == nil {
+		clock = chronon.SystemClock()
+	}
+
+	var (
+		parallel = max(sc.Parallel, 1)
+		results  = make(chan scheduleResult, len(actions))
+		started  = make([]bool, len(actions))
+		finished = make([]bool, len(actions))
+
+		running, done int
+		isStopped     bool
+	)
+
+	for done < len(actions) {
+		next := -1
+		for i := 0; running < parallel && next < 0 && i < len(actions); i++ {
+			if !started[i] && sc.ready(i, actions, finished) {
+				next = i
+			}
+		}
+
+		if next >= 0 {
+			a := actions[next]
+			started[next] = true
+			running++
+			l.Printf("[%s]", a.String())
+
+			switch {
+			case aborted:
+				results <- scheduleResult{i: next, err: ErrTriggerAborted}
+
+			case isStopped:
+				go func(i int) {
+					results <- scheduleResult{i: i, err: retryAction(stopped, l, clock, a, d, retryPolicy(a))}
+				}(next)
+
+			default:
+				go func(i int) {
+					results <- scheduleResult{i: i, err: retryAction(ctx, l, clock, a, d, retryPolicy(a))}
+				}(next)
+			}
+
+			continue
+		}
+
+		r := <-results
+		a := actions[r.i]
+		finished[r.i] = true
+		running--
+		done++
+
+		if r.err != nil && parallel > 1 {
+			l.Printf("action error: %s [action=%s]", r.err, a)
+		} else if r.err != nil {
+			l.Printf("action error: %s", r.err)
+		}
+
+		if r.err != nil && !skippedError(r.err) {
+			switch retryPolicy(a).OnFailure {
+			case FailureStopRemaining:
+				isStopped = true
+				stop(ErrTriggerStopped)
+
+			case FailureAbortAndKeepRunning:
+				aborted = true
+			}
+		}
+
+		if onResult != nil {
+			onResult(a, r.err)
+		}
+	}
+
+	return
+}
+
+// ParseAfter parses the dependencies between the --exec commands of a command line.
+// Each --after value is of the form command=command[,command...], e.g. 3=1,2, in
+// which the third command waits for the first and second.  Commands are counted
+// from 1, and a command may only wait on earlier commands.  The returned map is
+// suitable for Schedule.After.
+func ParseAfter(cl CommandLine) (map[int][]int, error) {
+	var after map[int][]int
+	for _, v := range cl.After {
+		c, deps, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, ErrInvalidAfter
+		}
+
+		i, err := parsePosition(c, len(cl.Exec))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dep := range strings.Split(deps, ",") {
+			j, err := parsePosition(dep, i)
+			if err != nil {
+				return nil, err
+			}
+
+			if after == nil {
+				after = make(map[int][]int)
+			}
+
+			after[i] = append(after[i], j)
+		}
+	}
+
+	return after, nil
+}
+
+// parsePosition parses a 1-based command position, returning the 0-based index.
+// The index must be less than the given limit.
+func parsePosition(v string, limit int) (int, error) {
+	p, err := strconv.Atoi(strings.TrimSpace(v))
+	switch {
+	case err != nil:
+		return 0, errors.Join(ErrInvalidAfter, err)
+
+	case p < 1 || p > limit:
+		return 0, ErrInvalidAfter
+
+	default:
+		return p - 1, nil
+	}
+}
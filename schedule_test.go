@@ -0,0 +1,256 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/fx"
+)
+
+// gatedAction is a ContextAction which signals when it starts, and then runs
+// until it is released.  Like an ExecAction, it does not start once its context
+// is done.
+type gatedAction struct {
+	name    string
+	started chan struct{}
+	release chan struct{}
+	err     error
+}
+
+func newGatedAction(name string) *gatedAction {
+	return &gatedAction{
+		name:    name,
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (ga *gatedAction) String() string {
+	return ga.name
+}
+
+func (ga *gatedAction) Run() error {
+	return ga.RunContext(context.Background(), nil)
+}
+
+func (ga *gatedAction) RunContext(ctx context.Context, _ Details) error {
+	if err := skipped(ctx); err != nil {
+		return err
+	}
+
+	close(ga.started)
+	<-ga.release
+	return ga.err
+}
+
+// isStarted returns true if this action has started running.
+func (ga *gatedAction) isStarted() bool {
+	select {
+	case <-ga.started:
+		return true
+
+	default:
+		return false
+	}
+}
+
+type ScheduleSuite struct {
+	DMSSuite
+}
+
+// runSchedule runs the given schedule in the background, returning a channel that
+// receives the results in the order the actions finished.
+func (suite *ScheduleSuite) runSchedule(sc Schedule, actions ...Action) <-chan []string {
+	var (
+		lock     sync.Mutex
+		finished []string
+		done     = make(chan []string, 1)
+	)
+
+	go func() {
+		runSchedule(context.Background(), suite.logger, nil, sc, func(a Action, err error) {
+			lock.Lock()
+			finished = append(finished, a.String())
+			lock.Unlock()
+		}, actions...)
+
+		done <- finished
+	}()
+
+	return done
+}
+
+// newFailingAction returns an action which fails with the given failure policy.
+func (suite *ScheduleSuite) newFailingAction(onFailure string) retryingAction {
+	return retryingAction{
+		mockAction: &mockAction{label: "failing"},
+		policy:     RetryPolicy{OnFailure: onFailure},
+	}
+}
+
+// waitForStart waits for each of the given actions to start.
+func (suite *ScheduleSuite) waitForStart(actions ...*gatedAction) {
+	for _, a := range actions {
+		select {
+		case <-a.started:
+		case <-time.After(5 * time.Second):
+			suite.FailNow("action did not start", a.name)
+		}
+	}
+}
+
+func (suite *ScheduleSuite) TestSequential() {
+	var (
+		a, b, c = newGatedAction("a"), newGatedAction("b"), newGatedAction("c")
+
+		// dependencies are ignored, since actions already run in order
+		done = suite.runSchedule(Schedule{After: map[int][]int{2: {0}}}, a, b, c)
+	)
+
+	suite.waitForStart(a)
+	suite.False(b.isStarted())
+	close(a.release)
+
+	suite.waitForStart(b)
+	suite.False(c.isStarted())
+	close(b.release)
+
+	suite.waitForStart(c)
+	close(c.release)
+	suite.Equal([]string{"a", "b", "c"}, <-done)
+}
+
+func (suite *ScheduleSuite) TestParallel() {
+	var (
+		a, b, c = newGatedAction("a"), newGatedAction("b"), newGatedAction("c")
+		done    = suite.runSchedule(Schedule{Parallel: 2}, a, b, c)
+	)
+
+	// at most two actions run at once
+	suite.waitForStart(a, b)
+	suite.False(c.isStarted())
+
+	close(b.release)
+	suite.waitForStart(c)
+	close(c.release)
+	close(a.release)
+
+	finished := <-done
+	suite.Len(finished, 3)
+	suite.Equal("b", finished[0])
+}
+
+func (suite *ScheduleSuite) TestAfter() {
+	var (
+		notify1  = newGatedAction("notify1")
+		notify2  = newGatedAction("notify2")
+		cleanup  = newGatedAction("cleanup")
+		shutdown = new(mockShutdowner)
+		done     = suite.runSchedule(
+			Schedule{Parallel: 4, After: map[int][]int{2: {1}}},
+			notify1, notify2, cleanup, ShutdownerAction{Shutdowner: shutdown},
+		)
+	)
+
+	// the cleanup waits for the second notification only
+	suite.waitForStart(notify1, notify2)
+	suite.False(cleanup.isStarted())
+	close(notify2.release)
+	suite.waitForStart(cleanup)
+
+	// the shutdown is a barrier that waits on everything before it
+	shutdown.On("Shutdown", []fx.ShutdownOption(nil)).Return(error(nil)).Once()
+	close(cleanup.release)
+	time.Sleep(50 * time.Millisecond)
+	shutdown.AssertNotCalled(suite.T(), "Shutdown", []fx.ShutdownOption(nil))
+
+	close(notify1.release)
+	finished := <-done
+	suite.Len(finished, 4)
+	suite.Equal("Shutdowner", finished[3])
+	shutdown.AssertExpectations(suite.T())
+}
+
+func (suite *ScheduleSuite) TestDryRunBarrier() {
+	var (
+		a    = newGatedAction("a")
+		done = suite.runSchedule(
+			Schedule{Parallel: 2},
+			a, DryRunAction{Logger: suite.logger, Action: ShutdownerAction{}},
+		)
+	)
+
+	// a dry run of the shutdown is still a barrier that waits on everything before it
+	suite.waitForStart(a)
+	time.Sleep(50 * time.Millisecond)
+	close(a.release)
+	suite.Equal([]string{"a", "Shutdowner"}, <-done)
+}
+
+func (suite *ScheduleSuite) TestStopRemaining() {
+	var (
+		failing  = suite.newFailingAction(FailureStopRemaining)
+		running  = newGatedAction("running")
+		skipped  = newGatedAction("skipped")
+		results  = make(map[string]error)
+		finished = make(chan struct{})
+	)
+
+	failing.ExpectRun().Return(errors.New("expected")).Once()
+	go func() {
+		defer close(finished)
+		runSchedule(
+			context.Background(),
+			suite.logger,
+			nil,
+			Schedule{Parallel: 2, After: map[int][]int{2: {0, 1}}},
+			func(a Action, err error) { results[a.String()] = err },
+			running, failing, skipped,
+		)
+	}()
+
+	// the running action is not affected by the failure, but the skipped action never starts
+	suite.waitForStart(running)
+	close(running.release)
+	<-finished
+
+	suite.NoError(results["running"])
+	suite.Error(results["failing"])
+	suite.ErrorIs(results["skipped"], ErrTriggerStopped)
+	suite.False(skipped.isStarted())
+	failing.AssertExpectations(suite.T())
+}
+
+func (suite *ScheduleSuite) TestParseAfter() {
+	suite.Run("Valid", func() {
+		after, err := ParseAfter(CommandLine{
+			Exec:  []string{"a", "b", "c", "d"},
+			After: []string{"3=1,2", "4=3", "4 = 1"},
+		})
+
+		suite.Require().NoError(err)
+		suite.Equal(map[int][]int{2: {0, 1}, 3: {2, 0}}, after)
+
+		after, err = ParseAfter(CommandLine{Exec: []string{"a"}})
+		suite.NoError(err)
+		suite.Nil(after)
+	})
+
+	suite.Run("Invalid", func() {
+		for _, v := range []string{"", "3", "3=", "x=1", "3=x", "5=1", "0=1", "2=2", "2=3", "1=2"} {
+			_, err := ParseAfter(CommandLine{Exec: []string{"a", "b", "c", "d"}, After: []string{v}})
+			suite.ErrorIs(err, ErrInvalidAfter, v)
+		}
+	})
+}
+
+func TestSchedule(t *testing.T) {
+	suite.Run(t, new(ScheduleSuite))
+}
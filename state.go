@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State is the persisted state of a Switch, which allows a restarted switch to
+// pick up its countdown where it left off.
+type State struct {
+	// LastPostpone is the time of the most recent postpone.
+	LastPostpone time.Time `json:"lastPostpone,omitzero"`
+
+	// Deadline is the time at which the switch next counts a miss.  This is
+	// the zero time if the switch has triggered.
+	Deadline time.Time `json:"deadline,omitzero"`
+
+	// Misses is the number of consecutive misses.
+	Misses int `json:"misses"`
+
+	// Triggered indicates whether the switch has triggered.  For a re-arming
+	// switch, this means the switch is tripped.
+	Triggered bool `json:"triggered"`
+
+	// TriggeredAt is the time at which the switch triggered.
+	TriggeredAt time.Time `json:"triggeredAt,omitzero"`
+}
+
+// String returns a human-readable representation of this state.
+func (st State) String() string {
+	if st.Triggered {
+		return fmt.Sprintf("[triggered=%s] [misses=%d]", st.TriggeredAt.Format(time.RFC3339), st.Misses)
+	}
+
+	return fmt.Sprintf("[deadline=%s] [misses=%d]", st.Deadline.Format(time.RFC3339), st.Misses)
+}
+
+// ReadState reads a State from the given file.  If the file does not exist,
+// this function returns an error that satisfies errors.Is(err, os.ErrNotExist).
+func ReadState(path string) (st State, err error) {
+	var data []byte
+	if data, err = os.ReadFile(path); err == nil {
+		err = json.Unmarshal(data, &st)
+	}
+
+	return
+}
+
+// WriteState atomically writes a State to the given file.
+func WriteState(path string, st State) error {
+	return writeJSON(path, st)
+}
+
+// writeJSON atomically writes a value as JSON to the given file.  The value is
+// first written to a temporary file in the same directory, which is then renamed
+// over the given file.  Readers therefore never see a partially written file.
+func writeJSON(path string, v any) (err error) {
+	var data []byte
+	if data, err = json.Marshal(v); err != nil {
+		return
+	}
+
+	var f *os.File
+	if f, err = os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp"); err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	err = errors.Join(err, f.Close())
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+
+	return
+}
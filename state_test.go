@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StateSuite struct {
+	DMSSuite
+}
+
+func (suite *StateSuite) TestReadWrite() {
+	var (
+		dir  = suite.T().TempDir()
+		path = filepath.Join(dir, "dms.state")
+		now  = time.Date(2026, time.January, 4, 2, 0, 0, 0, time.UTC)
+
+		expected = State{
+			LastPostpone: now,
+			Deadline:     now.Add(time.Minute),
+			Misses:       2,
+		}
+	)
+
+	_, err := ReadState(path)
+	suite.ErrorIs(err, os.ErrNotExist)
+
+	suite.Require().NoError(WriteState(path, expected))
+	actual, err := ReadState(path)
+	suite.Require().NoError(err)
+	suite.Equal(expected, actual)
+	suite.Equal("[deadline=2026-01-04T02:01:00Z] [misses=2]", actual.String())
+
+	// overwriting replaces the file, and leaves no temporary files behind
+	expected = State{Triggered: true, TriggeredAt: now}
+	suite.Require().NoError(WriteState(path, expected))
+	actual, err = ReadState(path)
+	suite.Require().NoError(err)
+	suite.Equal(expected, actual)
+	suite.Equal("[triggered=2026-01-04T02:00:00Z] [misses=0]", actual.String())
+
+	entries, err := os.ReadDir(dir)
+	suite.Require().NoError(err)
+	suite.Len(entries, 1)
+}
+
+func (suite *StateSuite) TestReadInvalid() {
+	path := filepath.Join(suite.T().TempDir(), "dms.state")
+	suite.Require().NoError(os.WriteFile(path, []byte(`{`), 0600))
+	_, err := ReadState(path)
+	suite.Error(err)
+}
+
+func (suite *StateSuite) TestWriteNoSuchDirectory() {
+	path := filepath.Join(suite.T().TempDir(), "nosuch", "dms.state")
+	suite.Error(WriteState(path, State{}))
+}
+
+func TestState(t *testing.T) {
+	suite.Run(t, new(StateSuite))
+}
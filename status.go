@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"time"
+)
+
+const (
+	// StateInactive is the state of a switch that has never been activated.
+	StateInactive = "inactive"
+
+	// StateActive is the state of a switch whose Activate loop is running.
+	StateActive = "active"
+
+	// StateTriggered is the state of a switch that triggered and stopped.
+	StateTriggered = "triggered"
+
+	// StateDeactivated is the state of a switch that was deactivated.
+	StateDeactivated = "deactivated"
+)
+
+const (
+	// ModeArmed is the mode of an active switch that is counting misses.
+	ModeArmed = "armed"
+
+	// ModeUnarmed is the mode of an active switch waiting for a grace period
+	// or for enough postpones to arm.
+	ModeUnarmed = "unarmed"
+
+	// ModeHeld is the mode of an active switch held unarmed by a trigger marker.
+	ModeHeld = "held"
+
+	// ModePaused is the mode of an active switch that has been paused.
+	ModePaused = "paused"
+
+	// ModeMaintenance is the mode of an active switch within a maintenance window.
+	ModeMaintenance = "maintenance"
+
+	// ModeTripped is the mode of an active, re-arming switch that has triggered.
+	ModeTripped = "tripped"
+)
+
+// PostponeStatus describes the most recent postpone received by a switch.
+type PostponeStatus struct {
+	Source     string    `json:"source,omitempty"`
+	RemoteAddr string    `json:"remoteAddr,omitempty"`
+	TTL        Duration  `json:"ttl,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// Status is a snapshot of a switch's state.
+type Status struct {
+	// State is one of the State constants.
+	State string `json:"state"`
+
+	// Mode is one of the Mode constants.  This is only set for an active switch.
+	Mode string `json:"mode,omitempty"`
+
+	TTL       Duration `json:"ttl"`
+	MaxMisses int      `json:"maxMisses"`
+	Misses    int      `json:"misses"`
+
+	// Deadline is the next time at which the switch will count a miss.  This is
+	// unset when the switch is not counting misses.
+	Deadline time.Time `json:"deadline,omitzero"`
+
+	// LastPostpone is the most recent postpone, if any.
+	LastPostpone *PostponeStatus `json:"lastPostpone,omitempty"`
+
+	// Actions are the switch's actions, by their String methods.
+	Actions []string `json:"actions"`
+}
+
+// StatusReporter represents something that can report a switch's status.
+type StatusReporter interface {
+	Status() Status
+}
+
+// Status returns a snapshot of this switch's state.  An active switch reports
+// the state as of the last event it handled.  Otherwise, the state at the end of
+// the most recent activation is returned.
+//
+// This method never waits on the Activate loop, so it returns promptly even
+// while this switch is running actions.
+func (s *Switch) Status() Status {
+	if st := s.snapshot.Load(); st != nil {
+		return *st
+	}
+
+	return s.status(StateInactive)
+}
+
+// status returns the parts of a Status that come from this switch's configuration.
+func (s *Switch) status(state string) Status {
+	st := Status{
+		State:     state,
+		TTL:       Duration(s.ttl),
+		MaxMisses: s.maxMisses,
+		Actions:   make([]string, 0, len(s.actions)),
+	}
+
+	for _, a := range s.actions {
+		st.Actions = append(st.Actions, a.String())
+	}
+
+	return st
+}
+
+// finish records the final status of an activation.
+func (s *Switch) finish(st Status) {
+	s.snapshot.Store(&st)
+}
+
+// publish records the current status of this loop, for Switch.Status.  Once the
+// loop is done, its final status has already been recorded.
+func (l *loop) publish() {
+	if l.done {
+		return
+	}
+
+	st := l.status(StateActive)
+	l.s.snapshot.Store(&st)
+}
+
+// status returns a snapshot of this loop's state.
+func (l *loop) status(state string) Status {
+	st := l.s.status(state)
+	if l.armed {
+		st.Misses = l.p.misses()
+	}
+
+	if !l.lastPostponed.IsZero() {
+		st.LastPostpone = &PostponeStatus{
+			Source:     l.lastRequest.Source,
+			RemoteAddr: l.lastRequest.RemoteAddr,
+			TTL:        Duration(l.lastRequest.TTL),
+			Time:       l.lastPostponed,
+		}
+	}
+
+	if state != StateActive {
+		return st
+	}
+
+	switch {
+	case l.held:
+		st.Mode = ModeHeld
+
+	case !l.armed:
+		st.Mode = ModeUnarmed
+
+	case l.tripped:
+		st.Mode = ModeTripped
+
+	case l.paused:
+		st.Mode = ModePaused
+
+	case !l.windowEnd.IsZero():
+		st.Mode = ModeMaintenance
+
+	default:
+		st.Mode = ModeArmed
+		st.Deadline = l.p.deadline()
+	}
+
+	return st
+}
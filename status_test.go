@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/xmidt-org/chronon"
+)
+
+type StatusSuite struct {
+	DMSSuite
+}
+
+func (suite *StatusSuite) TestInactive() {
+	var (
+		mockActions = newMockActions(2)
+		cfg, _      = suite.switchConfig(10*time.Second, 2, mockActions.actions()...)
+		s           = suite.newSwitch(cfg)
+	)
+
+	suite.Equal(
+		Status{
+			State:     StateInactive,
+			TTL:       Duration(10 * time.Second),
+			MaxMisses: 2,
+			Actions:   []string{"action[0]", "action[1]"},
+		},
+		s.Status(),
+	)
+}
+
+func (suite *StatusSuite) TestActive() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 1, mockActions.actions()...)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		cfg.ArmAfter = 1
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+		st := s.Status()
+		suite.Equal(StateActive, st.State)
+		suite.Equal(ModeUnarmed, st.Mode)
+		suite.True(st.Deadline.IsZero())
+		suite.Nil(st.LastPostpone)
+
+		suite.True(s.Postpone(PostponeRequest{Source: "test", RemoteAddr: "127.0.0.1:1234"}))
+		synctest.Wait()
+		st = s.Status()
+		suite.Equal(ModeArmed, st.Mode)
+		suite.True(st.Deadline.Equal(clock.Now().Add(ttl)))
+		suite.Equal(0, st.Misses)
+		suite.Require().NotNil(st.LastPostpone)
+		suite.Equal("test", st.LastPostpone.Source)
+		suite.Equal("127.0.0.1:1234", st.LastPostpone.RemoteAddr)
+		suite.True(st.LastPostpone.Time.Equal(clock.Now()))
+
+		suite.NoError(s.Pause(PauseRequest{Reason: "test", Duration: time.Minute}))
+		st = s.Status()
+		suite.Equal(ModePaused, st.Mode)
+		suite.True(st.Deadline.IsZero())
+
+		suite.NoError(s.Resume())
+		calls := mockActions.expectRunOnce(nil)
+		clock.Add(ttl)
+		mockActions.waitForCalls(suite.T(), time.Second, calls)
+		suite.NoError(<-done)
+		mockActions.assertExpectations(suite.T())
+
+		// the final status remains available after the switch stops
+		st = s.Status()
+		suite.Equal(StateTriggered, st.State)
+		suite.Empty(st.Mode)
+		suite.Equal(1, st.Misses)
+		suite.Require().NotNil(st.LastPostpone)
+		suite.Equal("test", st.LastPostpone.Source)
+	})
+}
+
+func (suite *StatusSuite) TestDeactivated() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			cfg, clock = suite.switchConfig(10*time.Second, 1)
+			done       = make(chan error)
+			onTicker   = make(chan chronon.FakeTicker, 1)
+		)
+
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+
+		st := s.Status()
+		suite.Equal(StateDeactivated, st.State)
+		suite.Empty(st.Mode)
+	})
+}
+
+func (suite *StatusSuite) TestRunningActions() {
+	var (
+		blocking = newBlockingAction()
+		cfg, clk = suite.switchConfig(10*time.Second, 1, blocking)
+		done     = make(chan error)
+		onTicker = make(chan chronon.FakeTicker, 1)
+	)
+
+	cfg.Rearm = true
+	s := suite.newSwitch(cfg)
+	clk.NotifyOnTicker(onTicker)
+	go func() {
+		done <- s.Activate()
+	}()
+
+	ft := <-onTicker
+	clk.Set(ft.When())
+	<-blocking.started
+
+	// status does not wait for the running action
+	for range 3 {
+		statuses := make(chan Status)
+		go func() {
+			statuses <- s.Status()
+		}()
+
+		select {
+		case st := <-statuses:
+			suite.Equal(StateActive, st.State)
+			suite.Equal(ModeTripped, st.Mode)
+			suite.Equal(1, st.Misses)
+
+		case <-time.After(time.Second):
+			suite.Require().Fail("Status blocked while an action was running")
+		}
+	}
+
+	suite.True(s.Abort())
+	suite.NoError(s.Deactivate())
+	suite.ErrorIs(<-done, ErrDeactivated)
+	suite.Equal(StateDeactivated, s.Status().State)
+}
+
+func (suite *StatusSuite) TestMarshal() {
+	data, err := json.Marshal(Status{
+		State:     StateActive,
+		Mode:      ModeArmed,
+		TTL:       Duration(time.Minute),
+		MaxMisses: 1,
+		Deadline:  time.Date(2026, time.January, 4, 2, 0, 0, 0, time.UTC),
+		Actions:   []string{},
+	})
+
+	suite.Require().NoError(err)
+	suite.JSONEq(
+		`{"state": "active", "mode": "armed", "ttl": "1m0s", "maxMisses": 1, "misses": 0, "deadline": "2026-01-04T02:00:00Z", "actions": []}`,
+		string(data),
+	)
+}
+
+func TestStatus(t *testing.T) {
+	suite.Run(t, new(StatusSuite))
+}
@@ -7,7 +7,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/xmidt-org/chronon"
@@ -22,9 +24,34 @@ const (
 	// when the TTL is nonpositive.
 	DefaultTTL time.Duration = 1 * time.Minute
 
+	// DefaultMinTTL is the smallest TTL a single postpone may request when no
+	// minimum is supplied or when the minimum is nonpositive.
+	DefaultMinTTL time.Duration = 1 * time.Second
+
+	// DefaultMaxTTL is the largest TTL a single postpone may request when no
+	// maximum is supplied or when the maximum is nonpositive.
+	DefaultMaxTTL time.Duration = 1 * time.Hour
+
+	// DefaultMaxPause is the longest pause that may be requested when no maximum
+	// is supplied or when the maximum is nonpositive.
+	DefaultMaxPause time.Duration = 24 * time.Hour
+
 	// DefaultMaxMisses is the number of allowed missed postpones before triggering
 	// actions when the misses are not supplied or are nonpositive.
 	DefaultMaxMisses = 0
+
+	// MaxQuorumSources is the maximum number of distinct sources that a quorum
+	// switch without expected sources tracks at once.  Postpones from further
+	// sources are ignored until an existing source expires.
+	MaxQuorumSources = 1000
+
+	// DefaultBudgetWindow is the number of TTL intervals over which MinPostpones
+	// applies when no window is supplied or when the window is nonpositive.
+	DefaultBudgetWindow = 10
+
+	// DefaultTriggerWindow is the window over which MaxTriggers applies when
+	// no window is supplied or when the window is nonpositive.
+	DefaultTriggerWindow time.Duration = 1 * time.Hour
 )
 
 var (
@@ -37,6 +64,34 @@ var (
 	// ErrDeactivated is returned by Activate if Deactivate was called before
 	// actions were triggered.
 	ErrDeactivated = errors.New("That switch has been deactivated")
+
+	// ErrNotTripped is returned by Rearm if a Switch is active but has not tripped.
+	ErrNotTripped = errors.New("That switch has not tripped")
+
+	// ErrNotArmed is returned by Pause if a Switch is active but is either
+	// unarmed or tripped.
+	ErrNotArmed = errors.New("That switch is not armed")
+
+	// ErrNotPaused is returned by Resume if a Switch is active but not paused.
+	ErrNotPaused = errors.New("That switch is not paused")
+
+	// ErrPauseReason is returned by Pause if no reason is supplied.
+	ErrPauseReason = errors.New("A reason is required to pause a switch")
+
+	// ErrPauseDuration is returned by Pause if the duration is nonpositive.
+	ErrPauseDuration = errors.New("A positive duration is required to pause a switch")
+
+	// ErrPauseTooLong is returned by Pause if the duration exceeds the switch's MaxPause.
+	ErrPauseTooLong = errors.New("The pause duration exceeds the maximum allowed")
+
+	// ErrTriggerReason is returned by Trigger if no reason is supplied.
+	ErrTriggerReason = errors.New("A reason is required to trigger a switch")
+
+	// ErrTripped is returned by Trigger if a Switch is active but has already tripped.
+	ErrTripped = errors.New("That switch has already tripped")
+
+	// ErrHeld is returned by Trigger if a Switch is active but held by a trigger marker.
+	ErrHeld = errors.New("That switch is held by a trigger marker")
 )
 
 // PostponeRequest carries information about a postponement to a Switch.
@@ -47,6 +102,14 @@ type PostponeRequest struct {
 	// RemoteAddr is the remote IP address from which the postpone request came.
 	// This field can be unset for requests which do not come from a network connection.
 	RemoteAddr string
+
+	// TTL is the optional interval before the next deadline, for this postpone only.
+	// If nonpositive, the switch's TTL is used.  A Switch caps this value at its MaxTTL.
+	TTL time.Duration
+
+	// Heartbeat is the optional health of the postponing service, which is checked
+	// against the switch's Rules.
+	Heartbeat *Heartbeat
 }
 
 // String returns a human-readable representation of this request.  This is the string
@@ -57,13 +120,31 @@ func (pr PostponeRequest) String() string {
 		source = DefaultSource
 	}
 
+	var suffix string
+	if pr.TTL > 0 {
+		suffix = fmt.Sprintf(" [ttl=%s]", pr.TTL)
+	}
+
+	if pr.Heartbeat != nil && len(pr.Heartbeat.Status) > 0 {
+		suffix += fmt.Sprintf(" [status=%s]", pr.Heartbeat.Status)
+	}
+
 	if len(pr.RemoteAddr) > 0 {
-		return fmt.Sprintf("[source=%s] [remoteaddr=%s]", source, pr.RemoteAddr)
+		return fmt.Sprintf("[source=%s] [remoteaddr=%s]%s", source, pr.RemoteAddr, suffix)
 	} else {
-		return fmt.Sprintf("[source=%s]", source)
+		return fmt.Sprintf("[source=%s]%s", source, suffix)
 	}
 }
 
+// ttl returns this request's TTL, or the given default if this request has no TTL.
+func (pr PostponeRequest) ttl(d time.Duration) time.Duration {
+	if pr.TTL > 0 {
+		return pr.TTL
+	}
+
+	return d
+}
+
 // Postponer represents something that can postpone triggering actions.
 type Postponer interface {
 	// Postpone issues a request that the action trigger be delayed by
@@ -72,6 +153,161 @@ type Postponer interface {
 	Postpone(PostponeRequest) bool
 }
 
+// TTLChecker is an optional interface for Postponers which bound the TTL that a
+// single postpone may request.
+type TTLChecker interface {
+	// MinTTL returns the smallest TTL that a single postpone may request.
+	MinTTL() time.Duration
+}
+
+// HeartbeatChecker is an optional interface for Postponers which check each
+// heartbeat against a set of rules.
+type HeartbeatChecker interface {
+	// FailedRules returns the rules that the given heartbeat does not pass.
+	FailedRules(*Heartbeat) Rules
+}
+
+// PauseRequest carries information about a pause to a Switch.
+type PauseRequest struct {
+	// Reason is the required explanation for the pause, e.g. a deploy ticket.
+	Reason string
+
+	// Duration is the required maximum length of the pause.  After this
+	// duration, the switch resumes by itself.
+	Duration time.Duration
+
+	// RemoteAddr is the remote IP address from which the pause request came.
+	// This field can be unset for requests which do not come from a network connection.
+	RemoteAddr string
+}
+
+// String returns a human-readable representation of this request.
+func (pr PauseRequest) String() string {
+	if len(pr.RemoteAddr) > 0 {
+		return fmt.Sprintf("[reason=%s] [duration=%s] [remoteaddr=%s]", pr.Reason, pr.Duration, pr.RemoteAddr)
+	} else {
+		return fmt.Sprintf("[reason=%s] [duration=%s]", pr.Reason, pr.Duration)
+	}
+}
+
+// Pauser represents something that can be paused for planned maintenance.
+type Pauser interface {
+	// Pause suspends counting misses until either Resume is called or the
+	// request's duration elapses.  Pausing a paused switch replaces the
+	// reason and duration of the current pause.
+	Pause(PauseRequest) error
+
+	// Resume ends a pause early.
+	Resume() error
+}
+
+// TriggerRequest carries information about a manual trigger to a Switch.
+type TriggerRequest struct {
+	// Source identifies who is triggering the switch.
+	Source string
+
+	// Reason is the required explanation for the trigger, e.g. an incident ticket.
+	Reason string
+
+	// RemoteAddr is the remote IP address from which the trigger request came.
+	// This field can be unset for requests which do not come from a network connection.
+	RemoteAddr string
+}
+
+// String returns a human-readable representation of this request.
+func (tr TriggerRequest) String() string {
+	source := tr.Source
+	if len(source) == 0 {
+		source = DefaultSource
+	}
+
+	if len(tr.RemoteAddr) > 0 {
+		return fmt.Sprintf("[source=%s] [reason=%s] [remoteaddr=%s]", source, tr.Reason, tr.RemoteAddr)
+	} else {
+		return fmt.Sprintf("[source=%s] [reason=%s]", source, tr.Reason)
+	}
+}
+
+// details returns the Details passed to actions for this request.
+func (tr TriggerRequest) details() Details {
+	d := Details{ReasonDetail: tr.Reason}
+	if len(tr.Source) > 0 {
+		d[SourceDetail] = tr.Source
+	}
+
+	return d
+}
+
+// Triggerer represents something that can be triggered on purpose.
+type Triggerer interface {
+	// Trigger runs the actions immediately, exactly as if the TTL had expired.
+	Trigger(TriggerRequest) error
+}
+
+// DeactivateRequest carries information about a remote deactivation of a Switch.
+type DeactivateRequest struct {
+	// Source identifies who is deactivating the switch.
+	Source string
+
+	// RemoteAddr is the remote IP address from which the deactivate request came.
+	// This field can be unset for requests which do not come from a network connection.
+	RemoteAddr string
+}
+
+// String returns a human-readable representation of this request.
+func (dr DeactivateRequest) String() string {
+	return PostponeRequest{Source: dr.Source, RemoteAddr: dr.RemoteAddr}.String()
+}
+
+// Deactivator represents something that can be stood down remotely.
+type Deactivator interface {
+	// StandDown deactivates without triggering any actions, and returns
+	// the final status.
+	StandDown(DeactivateRequest) (Status, error)
+}
+
+// Aborter represents something whose running actions can be stopped.
+type Aborter interface {
+	// Abort stops any running actions, returning false if no actions were running.
+	Abort() bool
+}
+
+// Rearmer represents something that can be re-armed after tripping.
+type Rearmer interface {
+	// Rearm re-arms a tripped switch.
+	Rearm() error
+}
+
+// SourceConfig describes a single expected source of postpones.
+type SourceConfig struct {
+	// Name is the PostponeRequest.Source value identifying this source.
+	Name string
+
+	// TTL is the interval on which this source must postpone.  If nonpositive,
+	// the switch's TTL is used.
+	TTL time.Duration
+
+	// MaxMisses is the number of intervals this source may miss before the switch
+	// triggers.  If nonpositive, the switch's MaxMisses is used.  This field is
+	// not used when the switch has a quorum.
+	MaxMisses int
+
+	// Weight is this source's contribution toward a switch's quorum.  If nonpositive,
+	// a weight of 1 is used.  This field is only used when the switch has a quorum.
+	Weight int
+}
+
+// Tier is a set of escalation actions which run once a switch has missed
+// a given number of postpones, before the switch itself triggers.
+type Tier struct {
+	// Misses is the number of consecutive missed postpones at which this tier's
+	// actions run.  If nonpositive, the tier runs on the first miss.
+	Misses int
+
+	// Actions are the tasks to run when this tier fires.
+	Actions []Action
+}
+
 // SwitchConfig represents the set of configurable options for a Switch.
 type SwitchConfig struct {
 	// Logger is the required sink for logging output.
@@ -84,17 +320,170 @@ type SwitchConfig struct {
 	// If nonpositive, DefaultTTL is used.
 	TTL time.Duration
 
+	// MinTTL is the smallest TTL that a single postpone may request.  Shorter
+	// requests are rejected, so that a postpone cannot trigger the switch at once.
+	//
+	// If nonpositive, DefaultMinTTL is used.  If greater than TTL, TTL is used.
+	MinTTL time.Duration
+
+	// MaxTTL is the largest TTL that a single postpone may request.  Longer
+	// requests are capped at this value.
+	//
+	// If nonpositive, DefaultMaxTTL is used.  If less than TTL, TTL is used.
+	MaxTTL time.Duration
+
+	// MaxPause is the longest pause that may be requested.  Longer requests
+	// are rejected, so that a pause cannot disable the switch indefinitely.
+	//
+	// If nonpositive, DefaultMaxPause is used.
+	MaxPause time.Duration
+
 	// MaxMisses is the number of missed postpones that are allowed before
 	// actions trigger.
 	//
 	// If nonpositive, DefaultMaxMisses is used.
 	MaxMisses int
 
+	// Sources are the optional expected sources of postpones.  When set, each source
+	// is tracked independently with its own TTL and miss budget, and the switch
+	// triggers as soon as any one of them goes silent.  Postpones from any other
+	// source are ignored.
+	//
+	// If unset, a postpone from any source resets the switch's miss count.
+	Sources []SourceConfig
+
+	// Quorum is the optional weighted number of distinct sources that must have
+	// postponed within their TTLs.  When set, the quorum is checked on each TTL
+	// interval, and each interval where fewer sources are live counts as a miss.
+	// If Sources is also set, only those sources count toward the quorum, using
+	// their weights.  Otherwise, every distinct source has a weight of 1.
+	//
+	// If nonpositive, no quorum is used.
+	Quorum int
+
+	// MinPostpones is the optional miss budget.  When set, the switch tracks its
+	// most recent BudgetWindow TTL intervals, and triggers as soon as fewer than
+	// MinPostpones of them had a postpone.  This catches a flaky sender which never
+	// misses MaxMisses intervals in a row.  MaxMisses is not used with a budget.
+	// This field is not used when the switch has Sources or a Quorum.
+	//
+	// If nonpositive, the switch triggers on consecutive misses.
+	MinPostpones int
+
+	// BudgetWindow is the number of TTL intervals over which MinPostpones applies.
+	//
+	// If nonpositive, DefaultBudgetWindow is used.  If less than MinPostpones,
+	// MinPostpones is used.
+	BudgetWindow int
+
+	// Tiers are optional escalation tiers.  Each tier fires at most once per
+	// outage, when the switch's miss count first reaches that tier's Misses.
+	// A tier fires again only after a postpone brings the miss count back below
+	// its threshold.  Tiers fire before Actions when both apply to the same miss.
+	Tiers []Tier
+
+	// RecoverActions are the optional tasks to run when postpones resume after
+	// one or more misses.  These actions receive the largest number of consecutive
+	// misses during the outage and the length of the outage, measured from the
+	// first detected miss.
+	RecoverActions []Action
+
 	// Actions are the set of tasks to trigger when the Switch's interval
 	// elapses without being postponed.  If this is an empty slice, then
 	// nothing happens when a switch is triggered.
 	Actions []Action
 
+	// Grace is an optional initial period after activation during which misses
+	// are not counted.  The switch arms, and its first TTL starts, once this
+	// period elapses.  If ArmAfter is also set, the switch arms at the end of
+	// the grace period or after ArmAfter postpones, whichever comes first.
+	Grace time.Duration
+
+	// ArmAfter is the optional number of consecutive postpones required to arm
+	// the switch after activation.  Postpones are consecutive if each arrives
+	// within the TTL of the previous one.  Until armed, misses are not counted.
+	//
+	// If nonpositive, the switch arms immediately or after the Grace period.
+	ArmAfter int
+
+	// Windows are optional recurring maintenance windows.  Misses are not counted
+	// while any window is open, and the TTL restarts when the window closes.
+	// Postpones are still accepted and logged during a window.
+	Windows []Window
+
+	// Rearm indicates whether this switch re-arms after triggering.  When set,
+	// the switch trips after running its actions rather than stopping.  A tripped
+	// switch is re-armed by a postpone or by an explicit call to Rearm.
+	Rearm bool
+
+	// Cooldown is the minimum time after a switch trips before a postpone
+	// will re-arm it.  Rearm ignores this cooldown.
+	Cooldown time.Duration
+
+	// MaxTriggers is the maximum number of times a re-arming switch will run its
+	// actions within TriggerWindow.  Beyond that, the switch still trips but
+	// its actions are suppressed.  If nonpositive, there is no limit.
+	MaxTriggers int
+
+	// TriggerWindow is the interval over which MaxTriggers applies.
+	//
+	// If nonpositive, DefaultTriggerWindow is used.
+	TriggerWindow time.Duration
+
+	// StateFile is the optional path of a file in which the switch persists its
+	// state.  When set, the state is written atomically on every change, and an
+	// activated switch picks up its countdown from this file rather than starting
+	// a new TTL.
+	StateFile string
+
+	// MarkerFile is the optional path of a trigger marker.  When set, a Marker is
+	// written to this file before the switch runs its actions.  A switch that finds
+	// this file when activated is held unarmed, and ignores postpones, until the
+	// file is removed.  This guarantees that actions do not run again after a restart.
+	MarkerFile string
+
+	// Rules are the optional checks that a postpone's Heartbeat must pass.  When
+	// set, a postpone that fails any rule, including one with no Heartbeat, does
+	// not postpone the switch.
+	Rules Rules
+
+	// Unhealthy is what a postpone that fails the Rules does.  UnhealthyMiss
+	// leaves the switch's deadline to pass, so that the interval counts as a miss.
+	// UnhealthyTrigger triggers the switch immediately, unless it is unarmed,
+	// paused, within a maintenance window, or tripped.
+	//
+	// If unset, UnhealthyMiss is used.
+	Unhealthy string
+
+	// Name is the name of a named switch, which is passed to its listeners.  This
+	// is unset for the default switch.
+	Name string
+
+	// Listeners are the optional listeners notified of this switch's events.
+	Listeners []SwitchListener
+
+	// TriggerTimeout is the optional time limit for all of the actions run by a
+	// single trigger, including escalation and recovery.  Commands are stopped when
+	// it elapses, and commands that have not started are skipped.  Other actions,
+	// such as shutting down, still run.  If nonpositive, there is no limit.
+	TriggerTimeout time.Duration
+
+	// Parallel is the maximum number of actions that a single trigger runs at once,
+	// including escalation and recovery.  If less than 2, actions run one at a time,
+	// in order.
+	Parallel int
+
+	// After maps the index of each of the Actions to the indexes of the earlier Actions
+	// which must finish before it starts.  This only applies when Parallel is at least 2.
+	// A ShutdownerAction always waits for every action before it.
+	After map[int][]int
+
+	// DryRun indicates that this switch's actions, including escalation and
+	// recovery actions, only log what they would do.  A dry run neither reads
+	// nor writes the StateFile or MarkerFile.  A dry run that does not Rearm
+	// re-arms immediately after triggering, rather than stopping.
+	DryRun bool
+
 	// Clock is the optional source of time information.  If unset,
 	// the system clock is used.
 	Clock chronon.Clock
@@ -106,8 +495,9 @@ type SwitchConfigIn struct {
 
 	Logger      Logger
 	Actions     []Action
-	CommandLine CommandLine   `optional:"true"`
-	Clock       chronon.Clock `optional:"true"`
+	CommandLine CommandLine      `optional:"true"`
+	Listeners   []SwitchListener `group:"listeners"`
+	Clock       chronon.Clock    `optional:"true"`
 }
 
 // provideSwitchConfig creates a SwitchConfig from injected components.
@@ -115,14 +505,65 @@ type SwitchConfigIn struct {
 // to the command line.
 func provideSwitchConfig() fx.Option {
 	return fx.Provide(
-		func(in SwitchConfigIn) SwitchConfig {
-			return SwitchConfig{
-				Logger:    in.Logger,
-				TTL:       in.CommandLine.TTL,
-				MaxMisses: in.CommandLine.Misses,
-				Actions:   in.Actions,
-				Clock:     in.Clock,
+		func(in SwitchConfigIn) (SwitchConfig, error) {
+			sources, err := ParseSources(in.CommandLine)
+			var tiers []Tier
+			if err == nil {
+				tiers, err = ParseTiers(in.CommandLine)
+			}
+
+			var recover []Action
+			if err == nil {
+				recover, err = ParseRecover(in.CommandLine)
+			}
+
+			var windows []Window
+			if err == nil {
+				windows, err = ParseWindows(in.CommandLine)
 			}
+
+			var rules Rules
+			if err == nil {
+				rules, err = ParseRules(in.CommandLine)
+			}
+
+			var after map[int][]int
+			if err == nil {
+				after, err = ParseAfter(in.CommandLine)
+			}
+
+			return SwitchConfig{
+				Logger:         in.Logger,
+				TTL:            in.CommandLine.TTL,
+				MinTTL:         in.CommandLine.MinTTL,
+				MaxTTL:         in.CommandLine.MaxTTL,
+				MaxPause:       in.CommandLine.MaxPause,
+				MaxMisses:      in.CommandLine.Misses,
+				Sources:        sources,
+				Quorum:         in.CommandLine.Quorum,
+				MinPostpones:   in.CommandLine.MinPostpones,
+				BudgetWindow:   in.CommandLine.BudgetWindow,
+				Tiers:          tiers,
+				RecoverActions: recover,
+				Actions:        in.Actions,
+				Grace:          in.CommandLine.Grace,
+				ArmAfter:       in.CommandLine.ArmAfter,
+				Windows:        windows,
+				Rearm:          in.CommandLine.Rearm,
+				Cooldown:       in.CommandLine.Cooldown,
+				MaxTriggers:    in.CommandLine.MaxTriggers,
+				TriggerWindow:  in.CommandLine.TriggerWindow,
+				StateFile:      in.CommandLine.StateFile,
+				MarkerFile:     in.CommandLine.MarkerFile,
+				TriggerTimeout: in.CommandLine.TriggerTimeout,
+				Parallel:       in.CommandLine.Parallel,
+				After:          after,
+				Rules:          rules,
+				Unhealthy:      in.CommandLine.Unhealthy,
+				Listeners:      in.Listeners,
+				DryRun:         in.CommandLine.DryRun,
+				Clock:          in.Clock,
+			}, err
 		},
 	)
 }
@@ -130,9 +571,13 @@ func provideSwitchConfig() fx.Option {
 // monitor holds the various concurrency primitives used by the Activate loop.
 type monitor struct {
 	postpone   <-chan PostponeRequest
+	commands   <-chan command
 	deactivate <-chan struct{}
 	exit       chan<- struct{}
 	actions    []Action
+
+	// ctx is the parent context of each trigger
+	ctx context.Context
 }
 
 // Switch is a dead man's switch.  This type is associated with a slice of Actions which
@@ -141,56 +586,218 @@ type Switch struct {
 	logger Logger
 
 	ttl       time.Duration
+	minTTL    time.Duration
+	maxTTL    time.Duration
+	maxPause  time.Duration
 	maxMisses int
-	actions   []Action
+	sources   []SourceConfig
+	quorum    int
+
+	minPostpones int
+	budgetWindow int
+
+	tiers   []Tier
+	recover []Action
+	actions []Action
+
+	grace    time.Duration
+	armAfter int
+	windows  []Window
+
+	rearm         bool
+	dryRun        bool
+	cooldown      time.Duration
+	maxTriggers   int
+	triggerWindow time.Duration
+
+	stateFile  string
+	markerFile string
+
+	rules            Rules
+	triggerUnhealthy bool
+
+	triggerTimeout time.Duration
+	parallel       int
+	after          map[int][]int
+
+	name     string
+	listener SwitchListeners
 
 	clock chronon.Clock
 
 	stateLock  sync.Mutex
 	postpone   chan<- PostponeRequest
+	commands   chan<- command
 	deactivate chan<- struct{}
+	stopped    <-chan struct{}
 	exit       <-chan struct{}
+
+	// snapshot is the status most recently published by the Activate loop, or
+	// the status at the end of the most recent activation.  This is updated
+	// atomically, so that Status never waits on running actions.
+	snapshot atomic.Pointer[Status]
+
+	// cancel stops the actions that are currently running, if any.  This is
+	// guarded by its own lock, since actions run under the state lock.
+	cancelLock sync.Mutex
+	cancel     context.CancelFunc
 }
 
 // NewSwitch constructs a Switch using the given set of configuration options.
 func NewSwitch(cfg SwitchConfig) *Switch {
 	s := &Switch{
-		logger:    cfg.Logger,
-		ttl:       cfg.TTL,
-		maxMisses: cfg.MaxMisses,
-		actions:   cfg.Actions,
-		clock:     cfg.Clock,
+		logger:         cfg.Logger,
+		ttl:            cfg.TTL,
+		minTTL:         cfg.MinTTL,
+		maxTTL:         cfg.MaxTTL,
+		maxPause:       cfg.MaxPause,
+		maxMisses:      cfg.MaxMisses,
+		sources:        cfg.Sources,
+		quorum:         cfg.Quorum,
+		minPostpones:   cfg.MinPostpones,
+		budgetWindow:   cfg.BudgetWindow,
+		tiers:          cfg.Tiers,
+		recover:        cfg.RecoverActions,
+		actions:        cfg.Actions,
+		grace:          cfg.Grace,
+		armAfter:       cfg.ArmAfter,
+		windows:        cfg.Windows,
+		rearm:          cfg.Rearm,
+		dryRun:         cfg.DryRun,
+		cooldown:       cfg.Cooldown,
+		maxTriggers:    cfg.MaxTriggers,
+		triggerWindow:  cfg.TriggerWindow,
+		stateFile:      cfg.StateFile,
+		markerFile:     cfg.MarkerFile,
+		rules:          cfg.Rules,
+		triggerTimeout: cfg.TriggerTimeout,
+		parallel:       cfg.Parallel,
+		after:          cfg.After,
+		name:           cfg.Name,
+		listener:       cfg.Listeners,
+		clock:          cfg.Clock,
 	}
 
 	if s.ttl <= 0 {
 		s.ttl = DefaultTTL
 	}
 
+	if s.minTTL <= 0 {
+		s.minTTL = DefaultMinTTL
+	}
+
+	if s.minTTL > s.ttl {
+		s.minTTL = s.ttl
+	}
+
+	if s.maxTTL <= 0 {
+		s.maxTTL = DefaultMaxTTL
+	}
+
+	if s.maxPause <= 0 {
+		s.maxPause = DefaultMaxPause
+	}
+
+	if s.maxTTL < s.ttl {
+		s.maxTTL = s.ttl
+	}
+
 	if s.maxMisses <= 0 {
 		s.maxMisses = DefaultMaxMisses
 	}
 
+	if s.budgetWindow <= 0 {
+		s.budgetWindow = DefaultBudgetWindow
+	}
+
+	if s.budgetWindow < s.minPostpones {
+		s.budgetWindow = s.minPostpones
+	}
+
+	if s.triggerWindow <= 0 {
+		s.triggerWindow = DefaultTriggerWindow
+	}
+
+	s.triggerUnhealthy = cfg.Unhealthy == UnhealthyTrigger
+
+	// copy the tiers, so that they can be normalized and sorted
+	s.tiers = append([]Tier(nil), s.tiers...)
+	for i := range s.tiers {
+		if s.tiers[i].Misses <= 0 {
+			s.tiers[i].Misses = 1
+		}
+	}
+
+	sort.SliceStable(s.tiers, func(i, j int) bool {
+		return s.tiers[i].Misses < s.tiers[j].Misses
+	})
+
 	if s.clock == nil {
 		s.clock = chronon.SystemClock()
 	}
 
+	if cfg.DryRun {
+		s.enableDryRun()
+	}
+
 	return s
 }
 
+// enableDryRun replaces this switch's actions with DryRunActions, and disables any
+// files that would affect a real switch.
+func (s *Switch) enableDryRun() {
+	s.logger.Printf("dry run: actions will be logged rather than run")
+	s.actions = DryRunActions(s.logger, s.actions)
+	s.recover = DryRunActions(s.logger, s.recover)
+	for i := range s.tiers {
+		s.tiers[i].Actions = DryRunActions(s.logger, s.tiers[i].Actions)
+	}
+
+	if len(s.stateFile) > 0 || len(s.markerFile) > 0 {
+		s.logger.Printf("dry run: ignoring the state file and trigger marker")
+		s.stateFile, s.markerFile = "", ""
+	}
+}
+
+// newPolicy creates the policy which decides when this switch triggers.
+func (s *Switch) newPolicy() policy {
+	if s.quorum > 0 {
+		return newQuorumPolicy(s.logger, s.ttl, s.maxMisses, s.quorum, s.sources)
+	}
+
+	if len(s.sources) > 0 {
+		return newSourcesPolicy(s.logger, s.ttl, s.maxMisses, s.sources)
+	}
+
+	if s.minPostpones > 0 {
+		return newBudgetPolicy(s.logger, s.ttl, s.minPostpones, s.budgetWindow)
+	}
+
+	return &consecutivePolicy{
+		logger:    s.logger,
+		ttl:       s.ttl,
+		maxMisses: s.maxMisses,
+	}
+}
+
 // initialize establishes the channels necessary to run this Switch.
 // If this switch is already running, an error is returned.
-func (s *Switch) initialize() (m monitor, err error) {
+func (s *Switch) initialize(ctx context.Context) (m monitor, err error) {
 	s.stateLock.Lock()
 	defer s.stateLock.Unlock()
 
 	if s.deactivate == nil {
 		m.actions = s.actions
+		m.ctx = ctx
 
 		postpone := make(chan PostponeRequest, 1)
 		s.postpone, m.postpone = postpone, postpone
 
+		commands := make(chan command)
+		s.commands, m.commands = commands, commands
+
 		deactivate := make(chan struct{})
-		s.deactivate, m.deactivate = deactivate, deactivate
+		s.deactivate, s.stopped, m.deactivate = deactivate, deactivate, deactivate
 
 		exit := make(chan struct{})
 		s.exit, m.exit = exit, exit
@@ -203,72 +810,115 @@ func (s *Switch) initialize() (m monitor, err error) {
 
 // terminate handles the common logic to shutdown this Switch.
 // When called with one or more actions, those actions are executed
-// under this switch's state lock.
+// under this switch's state lock and are passed the given context and details.
+// Postpones and commands wait for the actions, including any delays between
+// retries, which MaxRetryWait bounds for each action.  The trigger timeout
+// and Abort bound the actions as a whole.
 //
 // This method returns the exit channel that will be signaled when Activate
 // actually exits.  The returned channel will be nil if this switch was
-// not active.
+// not active, or if an action with the FailureAbortAndKeepRunning policy
+// failed, in which case this switch is not terminated and aborted is true.
 //
 // This method is passed the actions to trigger, rather than using the
 // Switch's actions.  This allows code to terminate without triggering
 // actions, such as in Deactivate.
-func (s *Switch) terminate(actions ...Action) (exit <-chan struct{}) {
+func (s *Switch) terminate(ctx context.Context, d Details, after map[int][]int, actions ...Action) (exit <-chan struct{}, aborted bool) {
 	s.stateLock.Lock()
 	defer s.stateLock.Unlock()
 
-	if s.deactivate != nil {
-		close(s.deactivate)
-		s.postpone = nil
-		s.deactivate = nil
-
-		exit, s.exit = s.exit, nil
+	if s.deactivate == nil {
+		return
+	}
 
-		// trigger actions under the state lock, to make Activate/Deactivate atomic
-		Trigger(s.logger, actions...)
+	// trigger actions under the state lock, to make Activate/Deactivate atomic
+	if aborted = s.trigger(ctx, d, after, actions...); aborted {
+		return
 	}
 
+	close(s.deactivate)
+	s.postpone = nil
+	s.commands = nil
+	s.deactivate = nil
+	s.stopped = nil
+
+	exit, s.exit = s.exit, nil
 	return
 }
 
-// Activate blocks until either the actions are triggered or Deactivate is invoked.
-// If this switch is already active, this method returns ErrActive.
-func (s *Switch) Activate() error {
-	m, err := s.initialize()
-	if err != nil {
-		return err
+// trigger runs actions, notifying this switch's listeners of each result.  The
+// actions are limited to this switch's trigger timeout, if it has one, and can
+// be stopped with Abort.  Up to this switch's parallel actions run at once,
+// subject to the given dependencies.  This method returns true if the actions
+// were aborted by an action with the FailureAbortAndKeepRunning policy.
+func (s *Switch) trigger(ctx context.Context, d Details, after map[int][]int, actions ...Action) bool {
+	var cancel context.CancelFunc
+	if s.triggerTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.triggerTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
 	}
 
-	defer close(m.exit)
+	s.cancelLock.Lock()
+	s.cancel = cancel
+	s.cancelLock.Unlock()
 
-	var misses int
-	t := s.clock.NewTicker(s.ttl)
-	defer t.Stop()
+	defer func() {
+		s.cancelLock.Lock()
+		s.cancel = nil
+		s.cancelLock.Unlock()
+		cancel()
+	}()
 
-	for {
-		select {
-		case pr := <-m.postpone:
-			t.Reset(s.ttl)
-			misses = 0
+	sc := Schedule{Parallel: s.parallel, After: after, Clock: s.clock}
+	return runSchedule(ctx, s.logger, d, sc, func(a Action, err error) {
+		s.listener.OnActionResult(s.name, s.clock.Now(), a, err)
+	}, actions...)
+}
 
-			s.logger.Printf("postponed %s", pr)
+// Abort stops the actions this switch is running, if any.  A running command is
+// terminated, and the remaining actions are skipped, apart from those which must
+// always run, such as a ShutdownerAction.  This method returns false if this
+// switch was not running any actions.
+//
+// Abort does not deactivate this switch.
+func (s *Switch) Abort() bool {
+	s.cancelLock.Lock()
+	cancel := s.cancel
+	s.cancelLock.Unlock()
 
-		case <-m.deactivate:
-			s.logger.Printf("deactivated")
-			return ErrDeactivated
+	if cancel == nil {
+		return false
+	}
 
-		case <-t.C():
-			misses++
-			s.logger.Printf("missed postpone update [misses=%d]", misses)
+	s.logger.Printf("aborting actions")
+	cancel()
+	return true
+}
 
-			if misses >= s.maxMisses {
-				if s.terminate(m.actions...) == nil {
-					return ErrDeactivated
-				}
+// Activate blocks until either the actions are triggered or Deactivate is invoked.
+// If this switch is already active, this method returns ErrActive.
+//
+// When this switch re-arms, Activate does not return after triggering actions.
+// Instead, the switch trips and waits to be re-armed.
+func (s *Switch) Activate() error {
+	return s.ActivateContext(context.Background())
+}
 
-				return nil
-			}
-		}
+// ActivateContext is like Activate, but each trigger's actions run with a context
+// derived from the given context.  Canceling the context stops any actions that are
+// running, just as Abort does, but does not deactivate this switch.
+func (s *Switch) ActivateContext(ctx context.Context) error {
+	m, err := s.initialize(ctx)
+	if err != nil {
+		return err
 	}
+
+	defer close(m.exit)
+	l := newLoop(s, m)
+	defer l.t.Stop()
+
+	return l.run()
 }
 
 // Deactivate forces Activate to return without triggering any actions.
@@ -277,7 +927,7 @@ func (s *Switch) Activate() error {
 //
 // This method blocks until the most recent invocation of Activate exits.
 func (s *Switch) Deactivate() (err error) {
-	if exit := s.terminate(); exit != nil {
+	if exit, _ := s.terminate(context.Background(), nil, nil); exit != nil {
 		<-exit
 	} else {
 		err = ErrNotActive
@@ -289,13 +939,146 @@ func (s *Switch) Deactivate() (err error) {
 // Postpone will delay triggering actions.  The miss count will be reset,
 // if applicable.  This method returns true to indicate that actions were
 // postponed, false if this switch was not active.
-func (s *Switch) Postpone(u PostponeRequest) (postponed bool) {
+//
+// If this switch has tripped, a postpone re-arms it once any cooldown has elapsed.
+// A postpone which requests a TTL longer than this switch's MaxTTL is capped.
+func (s *Switch) Postpone(u PostponeRequest) bool {
+	if u.TTL > s.maxTTL {
+		s.logger.Printf("capping requested ttl %s to %s", u.TTL, s.maxTTL)
+		u.TTL = s.maxTTL
+	}
+
 	s.stateLock.Lock()
-	defer s.stateLock.Unlock()
+	postpone, stopped := s.postpone, s.stopped
+	s.stateLock.Unlock()
+
+	if postpone == nil {
+		return false
+	}
+
+	// don't hold the state lock while sending, as the Activate loop
+	// may need that lock in order to receive
+	select {
+	case postpone <- u:
+		return true
 
-	if s.postpone != nil {
-		postponed = true
-		s.postpone <- u
+	case <-stopped:
+		return false
+	}
+}
+
+// MinTTL returns the smallest TTL that a single postpone to this switch may request.
+func (s *Switch) MinTTL() time.Duration {
+	return s.minTTL
+}
+
+// FailedRules returns the rules of this switch that the given heartbeat does not pass.
+// A heartbeat that fails any rule does not postpone this switch.
+func (s *Switch) FailedRules(hb *Heartbeat) Rules {
+	return s.rules.Failed(hb)
+}
+
+// do executes a command within the Activate loop, blocking until that command
+// has completed.  This method returns false if this switch was not active, in
+// which case the command was not executed.
+func (s *Switch) do(c command) bool {
+	s.stateLock.Lock()
+	commands, stopped := s.commands, s.stopped
+	s.stateLock.Unlock()
+
+	if commands == nil {
+		return false
+	}
+
+	done := make(chan struct{})
+	settled := func(l *loop) {
+		defer close(done)
+		c(l)
+		if !l.done {
+			// settle before returning, so that the caller observes the
+			// command's effects, such as a new deadline
+			l.settle()
+		}
+	}
+
+	select {
+	case commands <- settled:
+		<-done
+		return true
+
+	case <-stopped:
+		return false
+	}
+}
+
+// Rearm re-arms a tripped switch, regardless of any cooldown.  This method returns
+// ErrNotActive if this switch is not active, or ErrNotTripped if this switch is
+// active but has not tripped.
+func (s *Switch) Rearm() (err error) {
+	if !s.do(func(l *loop) { err = l.rearm() }) {
+		err = ErrNotActive
+	}
+
+	return
+}
+
+// Pause suspends counting misses.  Postpones are still accepted and logged
+// while paused.  When the pause ends, the switch's TTL restarts.
+//
+// This method returns ErrPauseTooLong if the requested duration exceeds this switch's
+// MaxPause, ErrNotActive if this switch is not active, and ErrNotArmed if this switch
+// is active but either unarmed or tripped.
+func (s *Switch) Pause(pr PauseRequest) (err error) {
+	switch {
+	case len(pr.Reason) == 0:
+		err = ErrPauseReason
+
+	case pr.Duration <= 0:
+		err = ErrPauseDuration
+
+	case pr.Duration > s.maxPause:
+		err = fmt.Errorf("%w of %s", ErrPauseTooLong, s.maxPause)
+
+	case !s.do(func(l *loop) { err = l.pause(pr) }):
+		err = ErrNotActive
+	}
+
+	return
+}
+
+// StandDown deactivates this switch on behalf of a remote caller, without triggering
+// any actions, and returns this switch's final status.  This method returns
+// ErrNotActive if this switch is not active.
+func (s *Switch) StandDown(dr DeactivateRequest) (Status, error) {
+	s.logger.Printf("deactivate requested %s", dr)
+	if err := s.Deactivate(); err != nil {
+		return Status{}, err
+	}
+
+	return s.Status(), nil
+}
+
+// Trigger immediately triggers this switch's actions, just as if its misses had been
+// exhausted.  A reason is required.  This method returns ErrNotActive if this switch
+// is not active, ErrTripped if this switch has already tripped, or ErrHeld if this
+// switch is held by a trigger marker.
+func (s *Switch) Trigger(tr TriggerRequest) (err error) {
+	switch {
+	case len(tr.Reason) == 0:
+		err = ErrTriggerReason
+
+	case !s.do(func(l *loop) { err = l.triggerNow(tr) }):
+		err = ErrNotActive
+	}
+
+	return
+}
+
+// Resume ends a pause, restarting this switch's TTL.  This method returns ErrNotActive
+// if this switch is not active, or ErrNotPaused if this switch is active but not paused.
+func (s *Switch) Resume() (err error) {
+	if !s.do(func(l *loop) { err = l.resume() }) {
+		err = ErrNotActive
 	}
 
 	return
@@ -311,6 +1094,24 @@ func provideSwitch() fx.Option {
 			func(s *Switch) Postponer {
 				return s
 			},
+			func(s *Switch) Rearmer {
+				return s
+			},
+			func(s *Switch) Pauser {
+				return s
+			},
+			func(s *Switch) StatusReporter {
+				return s
+			},
+			func(s *Switch) Triggerer {
+				return s
+			},
+			func(s *Switch) Deactivator {
+				return s
+			},
+			func(s *Switch) Aborter {
+				return s
+			},
 		),
 		fx.Invoke(
 			func(l fx.Lifecycle, s *Switch) {
@@ -320,6 +1121,8 @@ func provideSwitch() fx.Option {
 						return nil
 					},
 					OnStop: func(context.Context) (err error) {
+						// stop any actions in progress, so that deactivating doesn't wait on them
+						s.Abort()
 						if err = s.Deactivate(); errors.Is(err, ErrNotActive) {
 							// it's ok if something in the app deactivated the switch
 							// or if the switch triggered it's actions
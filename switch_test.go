@@ -4,12 +4,15 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"testing"
 	"testing/synctest"
 	"time"
 
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"github.com/xmidt-org/chronon"
 	"go.uber.org/fx"
@@ -40,8 +43,9 @@ func (suite *SwitchConfigSuite) TestProvideSwitchConfig() {
 
 		suite.Equal(
 			SwitchConfig{
-				Logger:  suite.logger,
-				Actions: actions,
+				Logger:    suite.logger,
+				Actions:   actions,
+				Listeners: []SwitchListener{},
 			},
 			cfg,
 		)
@@ -55,6 +59,7 @@ func (suite *SwitchConfigSuite) TestProvideSwitchConfig() {
 			mockActions = newMockActions(3)
 			actions     = mockActions.actions()
 			clock       = suite.clock()
+			listener    = new(mockSwitchListener)
 			cfg         SwitchConfig
 
 			app = fxtest.New(
@@ -62,14 +67,30 @@ func (suite *SwitchConfigSuite) TestProvideSwitchConfig() {
 				fx.Supply(
 					actions,
 					CommandLine{
-						TTL:    12 * time.Minute,
-						Misses: 7,
+						TTL:            12 * time.Minute,
+						Misses:         7,
+						Grace:          time.Hour,
+						ArmAfter:       2,
+						MinPostpones:   8,
+						BudgetWindow:   10,
+						TriggerTimeout: time.Minute,
+						Exec:           []string{"echo one", "echo two"},
+						Parallel:       2,
+						After:          []string{"2=1"},
+						Rule:           []string{"queue_depth < 1000"},
+						Unhealthy:      UnhealthyTrigger,
 					},
 				),
 				fx.Provide(
 					func() chronon.Clock {
 						return clock
 					},
+					fx.Annotate(
+						func() SwitchListener {
+							return listener
+						},
+						fx.ResultTags(`group:"listeners"`),
+					),
 				),
 				suite.provideLogger(),
 				provideSwitchConfig(),
@@ -81,11 +102,21 @@ func (suite *SwitchConfigSuite) TestProvideSwitchConfig() {
 
 		suite.Equal(
 			SwitchConfig{
-				Logger:    suite.logger,
-				Actions:   actions,
-				TTL:       12 * time.Minute,
-				MaxMisses: 7,
-				Clock:     clock,
+				Logger:         suite.logger,
+				Actions:        actions,
+				TTL:            12 * time.Minute,
+				MaxMisses:      7,
+				Grace:          time.Hour,
+				ArmAfter:       2,
+				MinPostpones:   8,
+				BudgetWindow:   10,
+				TriggerTimeout: time.Minute,
+				Parallel:       2,
+				After:          map[int][]int{1: {0}},
+				Rules:          Rules{{Field: "queue_depth", Operator: "<", Value: 1000.0}},
+				Unhealthy:      UnhealthyTrigger,
+				Listeners:      []SwitchListener{listener},
+				Clock:          clock,
 			},
 			cfg,
 		)
@@ -119,10 +150,36 @@ func (suite *SwitchSuite) TestDefaults() {
 
 		suite.Equal(suite.logger, s.logger)
 		suite.Equal(DefaultTTL, s.ttl)
+		suite.Equal(DefaultMinTTL, s.minTTL)
+		suite.Equal(DefaultMinTTL, s.MinTTL())
+		suite.Equal(DefaultMaxTTL, s.maxTTL)
+		suite.Equal(DefaultMaxPause, s.maxPause)
 		suite.Equal(DefaultMaxMisses, s.maxMisses)
 		suite.True(chronon.IsSystemClock(s.clock))
 	})
 
+	suite.Run("MaxTTLBelowTTL", func() {
+		s := suite.newSwitch(SwitchConfig{Logger: suite.logger, TTL: 2 * time.Hour})
+		suite.Equal(2*time.Hour, s.maxTTL)
+	})
+
+	suite.Run("MinTTLAboveTTL", func() {
+		s := suite.newSwitch(SwitchConfig{Logger: suite.logger, TTL: time.Minute, MinTTL: time.Hour})
+		suite.Equal(time.Minute, s.minTTL)
+	})
+
+	suite.Run("Budget", func() {
+		s := suite.newSwitch(SwitchConfig{Logger: suite.logger, MinPostpones: 8})
+		suite.Equal(DefaultBudgetWindow, s.budgetWindow)
+		suite.IsType((*budgetPolicy)(nil), s.newPolicy())
+
+		s = suite.newSwitch(SwitchConfig{Logger: suite.logger, MinPostpones: 8, BudgetWindow: 4})
+		suite.Equal(8, s.budgetWindow)
+
+		s = suite.newSwitch(SwitchConfig{Logger: suite.logger, MinPostpones: 8, Quorum: 2})
+		suite.IsType((*quorumPolicy)(nil), s.newPolicy())
+	})
+
 	suite.Run("provideSwitch", func() {
 		var (
 			mockActions = newMockActions(1)
@@ -274,6 +331,102 @@ func (suite *SwitchSuite) TestTrigger() {
 	}
 }
 
+// blockingAction is a ContextAction which runs until its context is done.
+type blockingAction struct {
+	started chan struct{}
+}
+
+func newBlockingAction() blockingAction {
+	return blockingAction{started: make(chan struct{})}
+}
+
+func (ba blockingAction) String() string {
+	return "blocking"
+}
+
+func (ba blockingAction) Run() error {
+	return ba.RunContext(context.Background(), nil)
+}
+
+func (ba blockingAction) RunContext(ctx context.Context, _ Details) error {
+	close(ba.started)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (suite *SwitchSuite) TestAbort() {
+	suite.Run("Abort", func() {
+		var (
+			blocking = newBlockingAction()
+			skipped  = newMockActions(1)
+			cfg, clk = suite.switchConfig(0, 0, append([]Action{blocking}, skipped.actions()...)...)
+			s        = suite.newSwitch(cfg)
+			done     = make(chan error)
+			onTicker = make(chan chronon.FakeTicker, 1)
+		)
+
+		suite.False(s.Abort())
+		clk.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		ft := <-onTicker
+		clk.Set(ft.When())
+		<-blocking.started
+		suite.True(s.Abort())
+
+		// the remaining action is skipped, since the trigger was canceled
+		suite.NoError(<-done)
+		suite.False(s.Abort())
+		skipped.assertExpectations(suite.T())
+	})
+
+	suite.Run("ActivateContext", func() {
+		var (
+			blocking    = newBlockingAction()
+			cfg, clk    = suite.switchConfig(0, 0, blocking)
+			s           = suite.newSwitch(cfg)
+			ctx, cancel = context.WithCancel(context.Background())
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		clk.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.ActivateContext(ctx)
+		}()
+
+		ft := <-onTicker
+		clk.Set(ft.When())
+		<-blocking.started
+		cancel()
+		suite.NoError(<-done)
+	})
+
+	suite.Run("OnStop", func() {
+		var (
+			blocking = newBlockingAction()
+			cfg, clk = suite.switchConfig(0, 0, blocking)
+			s        *Switch
+			p        Postponer
+			app      = suite.provideSwitch(cfg, &s, &p)
+			onTicker = make(chan chronon.FakeTicker, 1)
+		)
+
+		clk.NotifyOnTicker(onTicker)
+		app.RequireStart()
+
+		ft := <-onTicker
+		clk.Set(ft.When())
+		<-blocking.started
+
+		// stopping the app must not wait on the running action
+		app.RequireStop()
+		suite.False(s.Abort())
+	})
+}
+
 func (suite *SwitchSuite) testPostpone(ttl time.Duration, actionCount, maxMisses int) {
 	suite.Run("NewSwitch", func() {
 		synctest.Test(suite.T(), func(t *testing.T) {
@@ -374,6 +527,177 @@ func (suite *SwitchSuite) TestPostpone() {
 	}
 }
 
+func (suite *SwitchSuite) TestSources() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(2)
+			cfg, clock  = suite.switchConfig(ttl, 1, mockActions.actions()...)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		cfg.Sources = []SourceConfig{
+			{Name: "fast"},
+			{Name: "slow", TTL: 3 * ttl},
+		}
+
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		ft := <-onTicker
+		suite.True(ft.When().Equal(suite.now.Add(ttl)))
+
+		// keep only the fast source alive
+		clock.Add(ttl / 2)
+		suite.True(s.Postpone(PostponeRequest{Source: "fast"}))
+		suite.True(s.Postpone(PostponeRequest{Source: "unexpected"}))
+		synctest.Wait()
+
+		for i := 0; i < 4; i++ {
+			clock.Add(ttl / 2)
+			suite.True(s.Postpone(PostponeRequest{Source: "fast"}))
+			synctest.Wait()
+		}
+
+		// the slow source goes silent before the fast one
+		calls := mockActions.expectRunOnce(nil)
+		clock.Add(ttl / 2)
+		mockActions.waitForCalls(suite.T(), time.Second, calls)
+		suite.NoError(<-done)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *SwitchSuite) TestPostponeTTL() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(ttl, 1, mockActions.actions()...)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		cfg.MaxTTL = time.Minute
+		s := suite.newSwitch(cfg)
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		ft := <-onTicker
+
+		// the requested TTL sets the next deadline
+		suite.True(s.Postpone(PostponeRequest{Source: "test", TTL: 30 * time.Second}))
+		synctest.Wait()
+		suite.True(ft.When().Equal(suite.now.Add(30 * time.Second)))
+
+		// a requested TTL above the maximum is capped
+		suite.True(s.Postpone(PostponeRequest{Source: "test", TTL: time.Hour}))
+		synctest.Wait()
+		suite.True(ft.When().Equal(suite.now.Add(time.Minute)))
+
+		calls := mockActions.expectRunOnce(nil)
+		clock.Add(time.Minute)
+		mockActions.waitForCalls(suite.T(), time.Second, calls)
+		suite.NoError(<-done)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *SwitchSuite) TestStandDown() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			mockActions = newMockActions(1)
+			cfg, clock  = suite.switchConfig(time.Minute, 1, mockActions.actions()...)
+			s           = suite.newSwitch(cfg)
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+			dr          = DeactivateRequest{Source: "ops", RemoteAddr: "127.0.0.1:1234"}
+		)
+
+		suite.Equal("[source=ops] [remoteaddr=127.0.0.1:1234]", dr.String())
+		_, err := s.StandDown(dr)
+		suite.ErrorIs(err, ErrNotActive)
+
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		<-onTicker
+		st, err := s.StandDown(dr)
+		suite.NoError(err)
+		suite.Equal(StateDeactivated, st.State)
+		suite.ErrorIs(<-done, ErrDeactivated)
+
+		// no actions run, and the switch stays deactivated
+		clock.Add(time.Hour)
+		synctest.Wait()
+		_, err = s.StandDown(dr)
+		suite.ErrorIs(err, ErrNotActive)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
+func (suite *SwitchSuite) TestDryRun() {
+	synctest.Test(suite.T(), func(t *testing.T) {
+		var (
+			ttl         = 10 * time.Second
+			mockActions = newMockActions(3)
+			cfg, clock  = suite.switchConfig(ttl, 1, mockActions[0])
+			path        = filepath.Join(t.TempDir(), "dms.marker")
+			done        = make(chan error)
+			onTicker    = make(chan chronon.FakeTicker, 1)
+		)
+
+		listener := new(mockSwitchListener)
+		listener.ExpectOnMiss(mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		listener.ExpectOnTrigger(mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		listener.ExpectOnActionResult(mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		listener.ExpectOnDeactivate(mock.Anything, mock.Anything)
+
+		cfg.DryRun = true
+		cfg.Listeners = []SwitchListener{listener}
+		cfg.MarkerFile = path
+		cfg.StateFile = path + ".state"
+		cfg.RecoverActions = []Action{mockActions[1]}
+		cfg.Tiers = []Tier{{Misses: 1, Actions: []Action{mockActions[2]}}}
+		s := suite.newSwitch(cfg)
+		suite.Equal([]string{"action[0]"}, s.Status().Actions)
+
+		clock.NotifyOnTicker(onTicker)
+		go func() {
+			done <- s.Activate()
+		}()
+
+		// none of the mock actions are expected to run
+		<-onTicker
+		clock.Add(ttl)
+		synctest.Wait()
+		suite.NoFileExists(path)
+		suite.NoFileExists(cfg.StateFile)
+
+		// the switch re-armed rather than stopping, so a second deadline triggers it again
+		st := s.Status()
+		suite.Equal(StateActive, st.State)
+		suite.Equal(ModeArmed, st.Mode)
+		suite.True(st.Deadline.Equal(clock.Now().Add(ttl)))
+
+		clock.Add(ttl)
+		synctest.Wait()
+		listener.AssertNumberOfCalls(suite.T(), "OnTrigger", 2)
+
+		suite.NoError(s.Deactivate())
+		suite.ErrorIs(<-done, ErrDeactivated)
+		mockActions.assertExpectations(suite.T())
+	})
+}
+
 func TestSwitch(t *testing.T) {
 	suite.Run(t, new(SwitchSuite))
 }
@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidWindow is returned by ParseWindow to indicate a malformed --window value.
+	ErrInvalidWindow = errors.New("A window must be of the form cron;duration[;zone]")
+)
+
+// maxWindowExtensions limits how many overlapping openings Window.End will
+// follow, so that a window which never closes cannot loop forever.
+const maxWindowExtensions = 1024
+
+// Window is a recurring maintenance window, during which misses are not counted.
+type Window struct {
+	// Start is the schedule on which this window opens.
+	Start Cron
+
+	// Duration is how long this window stays open each time it opens.
+	Duration time.Duration
+
+	// Location is the time zone in which Start is evaluated.  If unset, UTC is used.
+	Location *time.Location
+}
+
+// String returns a human-readable representation of this window.
+func (w Window) String() string {
+	return fmt.Sprintf("[start=%s] [duration=%s] [zone=%s]", w.Start, w.Duration, w.location())
+}
+
+func (w Window) location() *time.Location {
+	if w.Location != nil {
+		return w.Location
+	}
+
+	return time.UTC
+}
+
+// Next returns the next time strictly after now that this window opens.
+func (w Window) Next(now time.Time) time.Time {
+	next := w.Start.Next(now.In(w.location()))
+	if next.IsZero() {
+		return next
+	}
+
+	return next.In(now.Location())
+}
+
+// End returns the time at which the window containing now closes.  If now
+// is not within this window, this method returns false.  Openings that
+// overlap an open window extend it.
+func (w Window) End(now time.Time) (end time.Time, open bool) {
+	if w.Duration <= 0 {
+		return
+	}
+
+	// the only openings that can contain now are those in (now-Duration, now]
+	start := w.Next(now.Add(-w.Duration))
+	if start.IsZero() || start.After(now) {
+		return
+	}
+
+	end = start.Add(w.Duration)
+	next := w.Next(start)
+	for i := 0; i < maxWindowExtensions && !next.IsZero() && !next.After(end); i++ {
+		end = next.Add(w.Duration)
+		next = w.Next(next)
+	}
+
+	return end, true
+}
+
+// ParseWindow parses a maintenance window from the command line.  The value is
+// of the form cron;duration[;zone], where the optional zone is an IANA time zone
+// name such as America/New_York.
+func ParseWindow(v string) (w Window, err error) {
+	pieces := strings.Split(v, ";")
+	if len(pieces) < 2 || len(pieces) > 3 {
+		return Window{}, ErrInvalidWindow
+	}
+
+	var d time.Duration
+	d, err = time.ParseDuration(pieces[1])
+	if err != nil {
+		return Window{}, errors.Join(ErrInvalidWindow, err)
+	}
+
+	var zone string
+	if len(pieces) > 2 {
+		zone = pieces[2]
+	}
+
+	return NewWindow(pieces[0], d, zone)
+}
+
+// NewWindow creates a Window from a cron expression, a duration, and an optional
+// IANA time zone name.
+func NewWindow(start string, d time.Duration, zone string) (w Window, err error) {
+	w.Duration = d
+	w.Start, err = ParseCron(start)
+	if err == nil && w.Duration <= 0 {
+		err = fmt.Errorf("duration %s is not positive", w.Duration)
+	}
+
+	if err == nil && len(zone) > 0 {
+		w.Location, err = time.LoadLocation(zone)
+	}
+
+	if err != nil {
+		return Window{}, errors.Join(ErrInvalidWindow, err)
+	}
+
+	return
+}
+
+// ParseWindows parses each of the --window values from a command line.
+func ParseWindows(cl CommandLine) ([]Window, error) {
+	var windows []Window
+	for _, v := range cl.Window {
+		w, err := ParseWindow(v)
+		if err != nil {
+			return nil, err
+		}
+
+		windows = append(windows, w)
+	}
+
+	return windows, nil
+}
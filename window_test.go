@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type WindowSuite struct {
+	DMSSuite
+}
+
+func (suite *WindowSuite) TestParseWindow() {
+	suite.Run("Valid", func() {
+		w, err := ParseWindow("0 2 * * SUN;2h;America/New_York")
+		suite.Require().NoError(err)
+		suite.Equal("0 2 * * SUN", w.Start.String())
+		suite.Equal(2*time.Hour, w.Duration)
+		suite.Equal("America/New_York", w.Location.String())
+		suite.Equal("[start=0 2 * * SUN] [duration=2h0m0s] [zone=America/New_York]", w.String())
+	})
+
+	suite.Run("UTC", func() {
+		w, err := ParseWindow("@daily;30m")
+		suite.Require().NoError(err)
+		suite.Nil(w.Location)
+		suite.Equal("[start=@daily] [duration=30m0s] [zone=UTC]", w.String())
+	})
+
+	suite.Run("Invalid", func() {
+		for _, v := range []string{
+			"",
+			"@daily",
+			"@daily;1h;UTC;extra",
+			"@nosuch;1h",
+			"@daily;nosuch",
+			"@daily;0s",
+			"@daily;1h;Nosuch/Zone",
+		} {
+			_, err := ParseWindow(v)
+			suite.ErrorIs(err, ErrInvalidWindow, v)
+		}
+	})
+
+	suite.Run("ParseWindows", func() {
+		windows, err := ParseWindows(CommandLine{Window: []string{"@daily;1h", "@weekly;2h"}})
+		suite.NoError(err)
+		suite.Len(windows, 2)
+
+		_, err = ParseWindows(CommandLine{Window: []string{"@daily;1h", "@daily"}})
+		suite.ErrorIs(err, ErrInvalidWindow)
+	})
+}
+
+func (suite *WindowSuite) TestEnd() {
+	var (
+		ny, _ = time.LoadLocation("America/New_York")
+
+		// Sunday 02:00-04:00 in New York
+		w, err = ParseWindow("0 2 * * SUN;2h;America/New_York")
+		opens  = time.Date(2026, time.January, 4, 2, 0, 0, 0, ny)
+		closes = opens.Add(2 * time.Hour)
+	)
+
+	suite.Require().NoError(err)
+
+	// the window is evaluated in its own zone, regardless of the zone of now
+	suite.True(opens.Equal(w.Next(opens.Add(-time.Hour).UTC())))
+
+	for _, now := range []time.Time{opens.Add(-time.Second), closes, closes.Add(time.Hour)} {
+		_, open := w.End(now)
+		suite.False(open, now.String())
+	}
+
+	for _, now := range []time.Time{opens, opens.Add(time.Hour), closes.Add(-time.Second)} {
+		end, open := w.End(now.UTC())
+		suite.True(open, now.String())
+		suite.True(closes.Equal(end), now.String())
+	}
+}
+
+func (suite *WindowSuite) TestEndOverlapping() {
+	var (
+		// opens every 10 minutes for 15 minutes, so it never closes
+		w, err = ParseWindow("*/10 * * * *;15m")
+		now    = time.Date(2026, time.January, 4, 2, 5, 0, 0, time.UTC)
+	)
+
+	suite.Require().NoError(err)
+	end, open := w.End(now)
+	suite.True(open)
+	suite.True(end.After(now.Add(time.Hour)))
+}
+
+func TestWindow(t *testing.T) {
+	suite.Run(t, new(WindowSuite))
+}